@@ -1,11 +1,21 @@
 package futures
 
 import (
+	"container/list"
 	"context"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// ErrFutureSucceeded is the error of a context from ContextFromFuture which was
+// aborted because its future succeeded
+var ErrFutureSucceeded = errors.New("future succeeded")
+
 // AbortContext extends the context.Context with support for custom errors
 type AbortContext interface {
 	context.Context
@@ -16,35 +26,198 @@ type AbortFunc func(error)
 
 // NewAbort creates a new base context and abort function
 func NewAbort() (AbortContext, AbortFunc) {
-	future, completeFunc := NewFuture()
-	ctx := &abortContext{
-		future:       future,
-		completeFunc: completeFunc,
-	}
+	ctx := newAbortContext()
 	return ctx, ctx.abort
 }
 
 // WithAbort is a parallel of context.WithCancel which wraps a context  and returns an abort fuction
 func WithAbort(ctx context.Context) (AbortContext, AbortFunc) {
-	future, completeFunc := NewFuture()
-	abortCtx := &abortContext{
-		inner:        ctx,
-		future:       future,
-		completeFunc: completeFunc,
+	abortCtx := newAbortContext(ctx)
+	abortCtx.propagate()
+	return abortCtx, abortCtx.abort
+}
+
+// WithAbortAny is a parallel of WithAbort for multiple parents; it aborts with the
+// error and cause of the first parent to end. Values are looked up in the parents
+// in order and the deadline is the earliest of the parents.
+func WithAbortAny(ctxs ...context.Context) (AbortContext, AbortFunc) {
+	abortCtx := newAbortContext(ctxs...)
+	abortCtx.propagate()
+	return abortCtx, abortCtx.abort
+}
+
+// WithAbortTimeout is a parallel of context.WithTimeout which aborts with err once the timeout passes;
+// a nil err defaults to context.DeadlineExceeded
+func WithAbortTimeout(ctx context.Context, timeout time.Duration, err error) (AbortContext, AbortFunc) {
+	return WithAbortDeadline(ctx, time.Now().Add(timeout), err)
+}
+
+// WithAbortDeadline is a parallel of context.WithDeadline which aborts with err once the deadline passes;
+// a nil err defaults to context.DeadlineExceeded
+func WithAbortDeadline(ctx context.Context, deadline time.Time, err error) (AbortContext, AbortFunc) {
+	if err == nil {
+		err = context.DeadlineExceeded
 	}
+	abortCtx := newAbortContext(ctx)
+	abortCtx.deadline = deadline
+	abortCtx.propagate()
+	wait := time.Until(deadline)
+	if wait <= 0 {
+		abortCtx.abort(err)
+		return abortCtx, abortCtx.abort
+	}
+	abortCtx.mu.Lock()
+	// the timer is only needed if no parent has aborted the context during propagate
+	select {
+	case <-abortCtx.Done():
+	default:
+		abortCtx.timer = time.AfterFunc(wait, func() {
+			abortCtx.abort(err)
+		})
+	}
+	abortCtx.mu.Unlock()
+	return abortCtx, abortCtx.abort
+}
+
+// Detach returns a context which keeps the values of ctx but is never done and has
+// no deadline, for background work which must outlive ctx; use WithAbort on it
+// to give that work its own abort
+func Detach(ctx context.Context) AbortContext {
+	return context.WithoutCancel(ctx)
+}
+
+// AbortFuture returns a future which completes with the error of ctx once it is done
+func AbortFuture(ctx context.Context) Future {
+	if a, ok := ctx.(*abortContext); ok {
+		return a.future
+	}
+	future, completeFunc := NewFuture()
+	OnAbort(ctx, func(err error) {
+		completeFunc(nil, err)
+	})
+	return future
+}
+
+// ContextFromFuture returns a context which aborts with the error of f if it fails,
+// and with ErrFutureSucceeded if it succeeds and abortOnSuccess is set
+func ContextFromFuture(parent context.Context, f Future, abortOnSuccess bool) (AbortContext, AbortFunc) {
+	abortCtx, abortFunc := WithAbort(parent)
 	go func() {
 		select {
-		case <-ctx.Done():
-			abortCtx.abort(ctx.Err())
+		case <-f.Done():
+			_, err := f.Result()
+			if err != nil {
+				abortFunc(err)
+			} else if abortOnSuccess {
+				abortFunc(ErrFutureSucceeded)
+			}
 		case <-abortCtx.Done():
 		}
 	}()
-	return abortCtx, abortCtx.abort
+	return abortCtx, abortFunc
+}
+
+func newAbortContext(parents ...context.Context) *abortContext {
+	future, completeFunc := NewFuture()
+	return &abortContext{
+		parents:      parents,
+		future:       future,
+		completeFunc: completeFunc,
+		node:         recordAbortContext(parents),
+	}
+}
+
+// abortContextKey is the key under which an abortContext returns itself from Value
+var abortContextKey int
+
+// parentAbortContext returns the abortContext behind parent if their Done channels
+// are the same, in which case it can propagate to children directly
+func parentAbortContext(parent context.Context) (*abortContext, bool) {
+	a, ok := parent.Value(&abortContextKey).(*abortContext)
+	if !ok || a.Done() != parent.Done() {
+		return nil, false
+	}
+	return a, true
+}
+
+// propagate aborts with the error of the first parent context to end without
+// starting goroutines; abortContext parents abort their children directly and
+// other parents are observed through context.AfterFunc
+func (a *abortContext) propagate() {
+	// like context.WithCancel, a parent which is already done aborts synchronously
+	for _, parent := range a.parents {
+		select {
+		case <-parent.Done():
+			a.abortFrom(parent)
+			return
+		default:
+		}
+	}
+	for _, parent := range a.parents {
+		parent := parent
+		if parent.Done() == nil {
+			continue
+		}
+		if p, ok := parentAbortContext(parent); ok {
+			if !p.addChild(a) {
+				a.abortFrom(parent)
+				return
+			}
+			a.mu.Lock()
+			a.registered = append(a.registered, p)
+			a.mu.Unlock()
+			continue
+		}
+		stop := context.AfterFunc(parent, func() {
+			a.abortFrom(parent)
+		})
+		a.mu.Lock()
+		a.stops = append(a.stops, stop)
+		a.mu.Unlock()
+	}
+	// a parent may have aborted during registration, before the lists were set
+	select {
+	case <-a.Done():
+		a.release()
+	default:
+	}
+}
+
+// addChild registers a child to abort with this context; it returns false if
+// this context is already done
+func (a *abortContext) addChild(child *abortContext) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	select {
+	case <-a.Done():
+		return false
+	default:
+	}
+	if a.children == nil {
+		a.children = make(map[*abortContext]struct{})
+	}
+	a.children[child] = struct{}{}
+	return true
+}
+
+func (a *abortContext) removeChild(child *abortContext) {
+	a.mu.Lock()
+	delete(a.children, child)
+	a.mu.Unlock()
 }
 
 type abortContext struct {
-	inner        context.Context
+	parents      []context.Context
+	deadline     time.Time
+	mu           sync.Mutex
+	timer        *time.Timer
+	children     map[*abortContext]struct{}
+	registered   []*abortContext
+	stops        []func() bool
+	callbacks    *list.List
+	node         *abortNode
 	once         sync.Once
+	cause        error
 	future       Future
 	completeFunc CompleteFunc
 }
@@ -64,31 +237,208 @@ func (a *abortContext) Err() error {
 }
 
 func (a *abortContext) Deadline() (time.Time, bool) {
-	if a.inner == nil {
-		return time.Time{}, false
+	deadline, ok := a.deadline, !a.deadline.IsZero()
+	for _, parent := range a.parents {
+		if parentDeadline, parentOk := parent.Deadline(); parentOk && (!ok || parentDeadline.Before(deadline)) {
+			deadline, ok = parentDeadline, true
+		}
 	}
-	return a.inner.Deadline()
+	return deadline, ok
 }
 
 func (a *abortContext) Value(key interface{}) interface{} {
-	if a.inner == nil {
-		return nil
+	if key == &abortContextKey {
+		return a
+	}
+	for _, parent := range a.parents {
+		if val := parent.Value(key); val != nil {
+			return val
+		}
 	}
-	return a.inner.Value(key)
+	return nil
 }
 
 func (a *abortContext) abort(err error) {
 	a.once.Do(func() {
-		// to avoid racing in cases where the complete call triggers on inner
-		// context cancel, always prefer the inner context before the abort
-		if a.inner != nil {
+		// to avoid racing in cases where the complete call triggers on parent
+		// context cancel, always prefer the parent contexts before the abort
+		for _, parent := range a.parents {
 			select {
-			case <-a.inner.Done():
-				a.completeFunc(nil, a.inner.Err())
+			case <-parent.Done():
+				a.complete(parent.Err(), chainCause(err, Cause(parent)))
 				return
 			default:
 			}
 		}
-		a.completeFunc(nil, err)
+		a.complete(err, err)
+	})
+	a.release()
+}
+
+// abortFrom aborts with the error and cause of a parent context which is done
+func (a *abortContext) abortFrom(parent context.Context) {
+	a.once.Do(func() {
+		a.complete(parent.Err(), Cause(parent))
+	})
+	a.release()
+}
+
+// complete must be called at most once, within once
+func (a *abortContext) complete(err error, cause error) {
+	a.cause = cause
+	if a.node != nil {
+		a.node.finish(err, cause)
+	}
+	a.completeFunc(nil, err)
+}
+
+// release stops the observation of parents and aborts the children once done
+func (a *abortContext) release() {
+	a.mu.Lock()
+	timer, children, registered, stops := a.timer, a.children, a.registered, a.stops
+	a.timer, a.children, a.registered, a.stops = nil, nil, nil, nil
+	var callbacks []func()
+	if a.callbacks != nil {
+		for elem := a.callbacks.Front(); elem != nil; elem = elem.Next() {
+			callbacks = append(callbacks, elem.Value.(func()))
+			elem.Value = nil
+		}
+		a.callbacks = nil
+	}
+	a.mu.Unlock()
+	if timer != nil {
+		timer.Stop()
+	}
+	for _, stop := range stops {
+		stop()
+	}
+	for _, parent := range registered {
+		parent.removeChild(a)
+	}
+	for child := range children {
+		child.abortFrom(a)
+	}
+	for _, callback := range callbacks {
+		callback()
+	}
+}
+
+// abortPanicHandler holds the func(*PanicError) which receives the panics captured
+// from OnAbort callbacks
+var abortPanicHandler atomic.Value
+
+func init() {
+	abortPanicHandler.Store(logAbortPanic)
+}
+
+// SetAbortPanicHandler sets the handler of the panics captured from OnAbort
+// callbacks; by default, or if handler is nil, they are logged with their stack
+func SetAbortPanicHandler(handler func(*PanicError)) {
+	if handler == nil {
+		handler = logAbortPanic
+	}
+	abortPanicHandler.Store(handler)
+}
+
+func logAbortPanic(p *PanicError) {
+	log.Printf("futures: OnAbort callback %v\n%s", p, p.Stack)
+}
+
+// OnAbort registers f to be called with the error of ctx once it is done; stop
+// unregisters f and returns false if f has already been called or stopped. On an
+// AbortContext the callbacks run in registration order in the goroutine which
+// aborts it, and f is called immediately if ctx is already done.
+func OnAbort(ctx context.Context, f func(err error)) (stop func() bool) {
+	callback := func() {
+		runAbortCallback(f, ctx.Err())
+	}
+	if a, ok := parentAbortContext(ctx); ok {
+		return a.onDone(callback)
+	}
+	return context.AfterFunc(ctx, callback)
+}
+
+// AfterFunc lets context.AfterFunc, and the contexts of the standard library
+// derived from an AbortContext, observe it without starting a goroutine
+func (a *abortContext) AfterFunc(f func()) func() bool {
+	return a.onDone(func() {
+		go f()
 	})
 }
+
+// onDone adds a callback to run on release, or runs it now if already done
+func (a *abortContext) onDone(callback func()) func() bool {
+	a.mu.Lock()
+	select {
+	case <-a.Done():
+		a.mu.Unlock()
+		callback()
+		return func() bool { return false }
+	default:
+	}
+	if a.callbacks == nil {
+		a.callbacks = list.New()
+	}
+	elem := a.callbacks.PushBack(callback)
+	a.mu.Unlock()
+	return func() bool {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		// the value is cleared once the callback has been claimed by release
+		if elem.Value == nil {
+			return false
+		}
+		a.callbacks.Remove(elem)
+		elem.Value = nil
+		return true
+	}
+}
+
+func runAbortCallback(f func(err error), err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			handler := abortPanicHandler.Load().(func(*PanicError))
+			handler(&PanicError{Value: r, Stack: debug.Stack()})
+		}
+	}()
+	f(err)
+}
+
+// Cause is a parallel of context.Cause which returns the full abort reason of an
+// AbortContext; use it instead of context.Cause, which does not see abort reasons
+func Cause(ctx context.Context) error {
+	if a, ok := ctx.(*abortContext); ok {
+		select {
+		case <-a.Done():
+			return a.cause
+		default:
+			return nil
+		}
+	}
+	return context.Cause(ctx)
+}
+
+// abortCause is the cause of a context aborted after its parent was done,
+// which keeps both the local abort reason and the cause of the parent
+type abortCause struct {
+	reason error
+	parent error
+}
+
+func chainCause(reason error, parent error) error {
+	if reason == nil || reason == parent {
+		return parent
+	}
+	return &abortCause{
+		reason: reason,
+		parent: parent,
+	}
+}
+
+func (c *abortCause) Error() string {
+	return fmt.Sprintf("%v (parent: %v)", c.reason, c.parent)
+}
+
+func (c *abortCause) Unwrap() []error {
+	return []error{c.reason, c.parent}
+}
@@ -1,9 +1,12 @@
 package futures
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"log"
+	"os"
+	"runtime"
 	"testing"
 	"time"
 
@@ -137,3 +140,413 @@ func TestAbortInner(t *testing.T) {
 	<-ctx.Done()
 	require.EqualError(t, ctx.Err(), expectedErr.Error())
 }
+
+func TestAbortTimeout(t *testing.T) {
+	expectedErr := errors.New("TestAbortTimeout")
+	abortCtx, abortFunc := WithAbortTimeout(context.Background(), 5*time.Millisecond, expectedErr)
+	defer abortFunc(nil)
+	select {
+	case <-abortCtx.Done():
+		require.Equal(t, expectedErr, abortCtx.Err())
+	case <-time.After(50 * time.Millisecond):
+		t.Fatal("abort not completed as expected")
+	}
+}
+
+func TestAbortDeadlinePassed(t *testing.T) {
+	expectedErr := errors.New("TestAbortDeadlinePassed")
+	abortCtx, abortFunc := WithAbortDeadline(context.Background(), time.Now().Add(-time.Second), expectedErr)
+	defer abortFunc(nil)
+	require.Equal(t, expectedErr, abortCtx.Err())
+}
+
+func TestAbortTimeoutNilErr(t *testing.T) {
+	abortCtx, abortFunc := WithAbortTimeout(context.Background(), time.Millisecond, nil)
+	defer abortFunc(nil)
+	<-abortCtx.Done()
+	require.Equal(t, context.DeadlineExceeded, abortCtx.Err())
+}
+
+func TestAbortTimeoutEarlyAbort(t *testing.T) {
+	expectedErr := errors.New("TestAbortTimeoutEarlyAbort")
+	abortCtx, abortFunc := WithAbortTimeout(context.Background(), time.Minute, errors.New("unexpected error"))
+	timer := abortCtx.(*abortContext).timer
+	abortFunc(expectedErr)
+	require.Equal(t, expectedErr, abortCtx.Err())
+	require.False(t, timer.Stop(), "timer not released on abort")
+}
+
+func TestAbortTimeoutInnerCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	abortCtx, abortFunc := WithAbortTimeout(ctx, time.Minute, errors.New("unexpected error"))
+	defer abortFunc(nil)
+	cancel()
+	<-abortCtx.Done()
+	require.Equal(t, context.Canceled, abortCtx.Err())
+}
+
+func TestAbortDeadlineTighter(t *testing.T) {
+	early := time.Now().Add(time.Minute)
+	late := early.Add(time.Minute)
+	ctx, cancel := context.WithDeadline(context.Background(), late)
+	defer cancel()
+	abortCtx, abortFunc := WithAbortDeadline(ctx, early, errors.New("TestAbortDeadlineTighter"))
+	defer abortFunc(nil)
+	deadline, ok := abortCtx.Deadline()
+	require.True(t, ok)
+	require.Equal(t, early, deadline)
+
+	innerCtx, innerCancel := context.WithDeadline(context.Background(), early)
+	defer innerCancel()
+	abortCtx, abortFunc = WithAbortDeadline(innerCtx, late, errors.New("TestAbortDeadlineTighter"))
+	defer abortFunc(nil)
+	deadline, ok = abortCtx.Deadline()
+	require.True(t, ok)
+	require.Equal(t, early, deadline)
+}
+
+func TestAbortCauseNotDone(t *testing.T) {
+	abortCtx, _ := NewAbort()
+	require.Nil(t, Cause(abortCtx))
+}
+
+func TestAbortCauseLocal(t *testing.T) {
+	abortCtx, abortFunc := WithAbort(context.Background())
+	expectedErr := errors.New("TestAbortCauseLocal")
+	abortFunc(expectedErr)
+	require.Equal(t, expectedErr, Cause(abortCtx))
+}
+
+func TestAbortCauseParent(t *testing.T) {
+	parentErr := errors.New("TestAbortCauseParent")
+	ctx, cancel := context.WithCancelCause(context.Background())
+	abortCtx, abortFunc := WithAbort(ctx)
+	defer abortFunc(nil)
+	cancel(parentErr)
+	<-abortCtx.Done()
+	require.Equal(t, context.Canceled, abortCtx.Err())
+	require.Equal(t, parentErr, Cause(abortCtx))
+}
+
+func TestAbortCauseChain(t *testing.T) {
+	parentErr := errors.New("TestAbortCauseChain parent")
+	localErr := errors.New("TestAbortCauseChain local")
+	ctx, cancel := context.WithCancelCause(context.Background())
+	abortCtx, abortFunc := WithAbort(ctx)
+	cancel(parentErr)
+	abortFunc(localErr)
+	<-abortCtx.Done()
+	require.Equal(t, context.Canceled, abortCtx.Err())
+	cause := Cause(abortCtx)
+	if cause != parentErr {
+		// the local abort won the race with propagation so both reasons are kept
+		require.True(t, errors.Is(cause, localErr))
+		require.EqualError(t, cause, "TestAbortCauseChain local (parent: TestAbortCauseChain parent)")
+	}
+	require.True(t, errors.Is(cause, parentErr))
+}
+
+func TestAbortCauseNested(t *testing.T) {
+	rootErr := errors.New("TestAbortCauseNested root")
+	root, rootAbort := NewAbort()
+	middle, middleAbort := WithAbort(root)
+	defer middleAbort(nil)
+	leaf, leafAbort := WithAbort(middle)
+	rootAbort(rootErr)
+	<-middle.Done()
+	leafErr := errors.New("TestAbortCauseNested leaf")
+	leafAbort(leafErr)
+	<-leaf.Done()
+	require.Equal(t, rootErr, leaf.Err())
+	require.True(t, errors.Is(Cause(leaf), rootErr))
+}
+
+func TestAbortAnyFirstParent(t *testing.T) {
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	defer cancel1()
+	ctx2, cancel2 := context.WithCancelCause(context.Background())
+	abortCtx, abortFunc := WithAbortAny(ctx1, ctx2)
+	defer abortFunc(nil)
+	expectedErr := errors.New("TestAbortAnyFirstParent")
+	cancel2(expectedErr)
+	select {
+	case <-abortCtx.Done():
+		require.Equal(t, context.Canceled, abortCtx.Err())
+		require.Equal(t, expectedErr, Cause(abortCtx))
+	case <-time.After(10 * time.Millisecond): // context cancelation is not instantaneous
+		t.Fatal("abort not completed as expected")
+	}
+}
+
+func TestAbortAnyNoParents(t *testing.T) {
+	abortCtx, abortFunc := WithAbortAny()
+	expectedErr := errors.New("TestAbortAnyNoParents")
+	abortFunc(expectedErr)
+	require.Equal(t, expectedErr, abortCtx.Err())
+}
+
+func TestAbortAnyValues(t *testing.T) {
+	key1, key2 := abortTestKey("key1"), abortTestKey("key2")
+	ctx1 := context.WithValue(context.Background(), key1, "value1")
+	ctx2 := context.WithValue(context.WithValue(context.Background(), key1, "shadowed"), key2, "value2")
+	abortCtx, abortFunc := WithAbortAny(ctx1, ctx2)
+	defer abortFunc(nil)
+	require.Equal(t, "value1", abortCtx.Value(key1))
+	require.Equal(t, "value2", abortCtx.Value(key2))
+	require.Nil(t, abortCtx.Value(abortTestKey("key3")))
+}
+
+func TestAbortAnyDeadline(t *testing.T) {
+	early := time.Now().Add(time.Minute)
+	ctx1, cancel1 := context.WithDeadline(context.Background(), early.Add(time.Minute))
+	defer cancel1()
+	ctx2, cancel2 := context.WithDeadline(context.Background(), early)
+	defer cancel2()
+	abortCtx, abortFunc := WithAbortAny(ctx1, context.Background(), ctx2)
+	defer abortFunc(nil)
+	deadline, ok := abortCtx.Deadline()
+	require.True(t, ok)
+	require.Equal(t, early, deadline)
+}
+
+func TestAbortAnyNoLeak(t *testing.T) {
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	defer cancel1()
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	defer cancel2()
+	before := runtime.NumGoroutine()
+	_, abortFunc := WithAbortAny(ctx1, ctx2)
+	require.Equal(t, before, runtime.NumGoroutine())
+	abortFunc(errors.New("TestAbortAnyNoLeak"))
+	require.Equal(t, before, runtime.NumGoroutine())
+}
+
+func TestAbortChildSynchronous(t *testing.T) {
+	parent, parentAbort := NewAbort()
+	child, childAbort := WithAbort(parent)
+	defer childAbort(nil)
+	expectedErr := errors.New("TestAbortChildSynchronous")
+	parentAbort(expectedErr)
+	require.Equal(t, expectedErr, child.Err())
+	require.Empty(t, parent.(*abortContext).children)
+}
+
+func TestAbortChildRemoved(t *testing.T) {
+	parent, parentAbort := NewAbort()
+	defer parentAbort(nil)
+	_, childAbort := WithAbort(parent)
+	childAbort(errors.New("TestAbortChildRemoved"))
+	require.Empty(t, parent.(*abortContext).children)
+	require.Nil(t, parent.Err())
+}
+
+func TestAbortValueWrappedParent(t *testing.T) {
+	parent, parentAbort := NewAbort()
+	ctx := context.WithValue(parent, abortTestKey("key"), "value")
+	child, childAbort := WithAbort(ctx)
+	defer childAbort(nil)
+	require.Len(t, parent.(*abortContext).children, 1)
+	expectedErr := errors.New("TestAbortValueWrappedParent")
+	parentAbort(expectedErr)
+	require.Equal(t, expectedErr, child.Err())
+	require.Equal(t, "value", child.Value(abortTestKey("key")))
+}
+
+func benchmarkWithAbort(b *testing.B, parent context.Context) {
+	b.ReportAllocs()
+	before := runtime.NumGoroutine()
+	aborts := make([]AbortFunc, 0, b.N)
+	for i := 0; i < b.N; i++ {
+		_, abortFunc := WithAbort(parent)
+		aborts = append(aborts, abortFunc)
+	}
+	b.StopTimer()
+	b.ReportMetric(float64(runtime.NumGoroutine()-before)/float64(b.N), "goroutines/op")
+	for _, abortFunc := range aborts {
+		abortFunc(nil)
+	}
+}
+
+func BenchmarkWithAbortParentAbort(b *testing.B) {
+	parent, abortFunc := NewAbort()
+	defer abortFunc(nil)
+	benchmarkWithAbort(b, parent)
+}
+
+func BenchmarkWithAbortParentCancel(b *testing.B) {
+	parent, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	benchmarkWithAbort(b, parent)
+}
+
+func BenchmarkWithAbortChain(b *testing.B) {
+	b.ReportAllocs()
+	before := runtime.NumGoroutine()
+	root, rootAbort := NewAbort()
+	var ctx context.Context = root
+	for i := 0; i < b.N; i++ {
+		ctx, _ = WithAbort(ctx)
+	}
+	b.StopTimer()
+	b.ReportMetric(float64(runtime.NumGoroutine()-before)/float64(b.N), "goroutines/op")
+	rootAbort(nil)
+	<-ctx.Done()
+}
+
+func TestOnAbortOrder(t *testing.T) {
+	abortCtx, abortFunc := NewAbort()
+	var calls []string
+	OnAbort(abortCtx, func(err error) { calls = append(calls, "first") })
+	stop := OnAbort(abortCtx, func(err error) { calls = append(calls, "stopped") })
+	OnAbort(abortCtx, func(err error) { calls = append(calls, "last") })
+	require.True(t, stop())
+	require.False(t, stop())
+	abortFunc(errors.New("TestOnAbortOrder"))
+	require.Equal(t, []string{"first", "last"}, calls)
+}
+
+func TestOnAbortErr(t *testing.T) {
+	parent, parentAbort := NewAbort()
+	abortCtx, abortFunc := WithAbort(parent)
+	defer abortFunc(nil)
+	var received error
+	stop := OnAbort(abortCtx, func(err error) { received = err })
+	expectedErr := errors.New("TestOnAbortErr")
+	parentAbort(expectedErr)
+	require.Equal(t, expectedErr, received)
+	require.False(t, stop())
+}
+
+func TestOnAbortDone(t *testing.T) {
+	abortCtx, abortFunc := NewAbort()
+	expectedErr := errors.New("TestOnAbortDone")
+	abortFunc(expectedErr)
+	var received error
+	stop := OnAbort(abortCtx, func(err error) { received = err })
+	require.Equal(t, expectedErr, received)
+	require.False(t, stop())
+}
+
+func TestOnAbortPanic(t *testing.T) {
+	panics := make(chan *PanicError, 1)
+	SetAbortPanicHandler(func(p *PanicError) { panics <- p })
+	defer SetAbortPanicHandler(nil)
+	abortCtx, abortFunc := NewAbort()
+	called := false
+	OnAbort(abortCtx, func(err error) { panic("TestOnAbortPanic") })
+	OnAbort(abortCtx, func(err error) { called = true })
+	require.NotPanics(t, func() {
+		abortFunc(nil)
+	})
+	require.True(t, called)
+	require.Equal(t, "TestOnAbortPanic", (<-panics).Value)
+}
+
+func TestOnAbortPanicLogged(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+	abortCtx, abortFunc := NewAbort()
+	OnAbort(abortCtx, func(err error) { panic("TestOnAbortPanicLogged") })
+	abortFunc(nil)
+	require.Contains(t, buf.String(), "panic: TestOnAbortPanicLogged")
+}
+
+func TestOnAbortContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	received := make(chan error, 1)
+	OnAbort(ctx, func(err error) { received <- err })
+	cancel()
+	require.Equal(t, context.Canceled, <-received)
+}
+
+func TestAbortAfterFunc(t *testing.T) {
+	abortCtx, abortFunc := NewAbort()
+	before := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(abortCtx)
+	defer cancel()
+	require.Equal(t, before, runtime.NumGoroutine())
+	expectedErr := errors.New("TestAbortAfterFunc")
+	abortFunc(expectedErr)
+	<-ctx.Done()
+	require.Equal(t, expectedErr, ctx.Err())
+}
+
+func TestDetach(t *testing.T) {
+	parent, parentAbort := WithAbortTimeout(context.WithValue(context.Background(), abortTestKey("key"), "value"), time.Minute, nil)
+	detached := Detach(parent)
+	parentAbort(errors.New("TestDetach"))
+	require.Nil(t, detached.Done())
+	require.Nil(t, detached.Err())
+	_, ok := detached.Deadline()
+	require.False(t, ok)
+	require.Equal(t, "value", detached.Value(abortTestKey("key")))
+}
+
+func TestDetachWithAbort(t *testing.T) {
+	parent, parentAbort := NewAbort()
+	abortCtx, abortFunc := WithAbort(Detach(parent))
+	parentAbort(errors.New("TestDetachWithAbort parent"))
+	require.Nil(t, abortCtx.Err())
+	expectedErr := errors.New("TestDetachWithAbort")
+	abortFunc(expectedErr)
+	require.Equal(t, expectedErr, abortCtx.Err())
+}
+
+func TestAbortFuture(t *testing.T) {
+	abortCtx, abortFunc := NewAbort()
+	future := AbortFuture(abortCtx)
+	requirePending(t, future)
+	expectedErr := errors.New("TestAbortFuture")
+	abortFunc(expectedErr)
+	_, err := future.Result()
+	require.Equal(t, expectedErr, err)
+}
+
+func TestAbortFutureContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	future := AbortFuture(ctx)
+	requirePending(t, future)
+	cancel()
+	_, err := future.Result()
+	require.Equal(t, context.Canceled, err)
+}
+
+func TestContextFromFutureFailure(t *testing.T) {
+	future, completeFunc := NewFuture()
+	abortCtx, abortFunc := ContextFromFuture(context.Background(), future, false)
+	defer abortFunc(nil)
+	require.Nil(t, abortCtx.Err())
+	expectedErr := errors.New("TestContextFromFutureFailure")
+	completeFunc(nil, expectedErr)
+	<-abortCtx.Done()
+	require.Equal(t, expectedErr, abortCtx.Err())
+}
+
+func TestContextFromFutureSuccess(t *testing.T) {
+	future, completeFunc := NewFuture()
+	abortCtx, abortFunc := ContextFromFuture(context.Background(), future, true)
+	defer abortFunc(nil)
+	completeFunc("TestContextFromFutureSuccess", nil)
+	<-abortCtx.Done()
+	require.Equal(t, ErrFutureSucceeded, abortCtx.Err())
+
+	future, completeFunc = NewFuture()
+	abortCtx, abortFunc = ContextFromFuture(context.Background(), future, false)
+	completeFunc("TestContextFromFutureSuccess", nil)
+	expectedErr := errors.New("TestContextFromFutureSuccess")
+	abortFunc(expectedErr)
+	require.Equal(t, expectedErr, abortCtx.Err())
+}
+
+func TestAbortParentDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	abortCtx, abortFunc := WithAbort(ctx)
+	defer abortFunc(nil)
+	require.Equal(t, context.Canceled, abortCtx.Err())
+	abortCtx, abortFunc = WithAbortTimeout(ctx, time.Minute, errors.New("unexpected error"))
+	defer abortFunc(nil)
+	require.Equal(t, context.Canceled, abortCtx.Err())
+	require.Nil(t, abortCtx.(*abortContext).timer, "timer not released")
+}
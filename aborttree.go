@@ -0,0 +1,222 @@
+package futures
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// AbortNode describes an AbortContext recorded by the abort registry
+type AbortNode struct {
+	ID        uint64       `json:"id"`
+	Site      string       `json:"site"`
+	Created   time.Time    `json:"created"`
+	State     string       `json:"state"`
+	Err       string       `json:"error,omitempty"`
+	Cause     string       `json:"cause,omitempty"`
+	AbortedAt time.Time    `json:"aborted_at"`
+	Children  []*AbortNode `json:"children,omitempty"`
+}
+
+const (
+	abortStatePending = "pending"
+	abortStateAborted = "aborted"
+)
+
+// currentAbortRegistry holds the *abortRegistry which records new contexts, or a nil one if disabled
+var currentAbortRegistry atomic.Value
+
+func init() {
+	currentAbortRegistry.Store((*abortRegistry)(nil))
+}
+
+// EnableAbortRegistry starts recording the AbortContexts created after this call,
+// replacing earlier records; pending contexts are kept until they are done, after
+// which the keepAborted most recently aborted ones are kept
+func EnableAbortRegistry(keepAborted int) {
+	currentAbortRegistry.Store(&abortRegistry{
+		nodes:       make(map[uint64]*abortNode),
+		aborted:     list.New(),
+		keepAborted: keepAborted,
+	})
+}
+
+// DisableAbortRegistry stops recording AbortContexts and drops the records
+func DisableAbortRegistry() {
+	currentAbortRegistry.Store((*abortRegistry)(nil))
+}
+
+// AbortTree returns the recorded AbortContexts as trees ordered by creation; a
+// context whose parent is not recorded is a root
+func AbortTree() []*AbortNode {
+	r := currentAbortRegistry.Load().(*abortRegistry)
+	if r == nil {
+		return nil
+	}
+	return r.tree()
+}
+
+// AbortTreeHandler renders the AbortTree as indented text, or as JSON if the
+// format query parameter is json
+func AbortTreeHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		tree := AbortTree()
+		if req.URL.Query().Get("format") == "json" {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(tree)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		writeAbortTree(w, tree, 0)
+	})
+}
+
+func writeAbortTree(w io.Writer, nodes []*AbortNode, depth int) {
+	for _, node := range nodes {
+		line := fmt.Sprintf("%s#%d %s %s created %s", strings.Repeat("  ", depth), node.ID, node.State, node.Site, node.Created.Format(time.RFC3339Nano))
+		if node.State == abortStateAborted {
+			line += fmt.Sprintf(" aborted %s: %s", node.AbortedAt.Format(time.RFC3339Nano), node.Cause)
+		}
+		fmt.Fprintln(w, line)
+		writeAbortTree(w, node.Children, depth+1)
+	}
+}
+
+type abortRegistry struct {
+	mu          sync.Mutex
+	lastID      uint64
+	nodes       map[uint64]*abortNode
+	aborted     *list.List
+	keepAborted int
+}
+
+type abortNode struct {
+	registry  *abortRegistry
+	id        uint64
+	parent    uint64
+	site      string
+	created   time.Time
+	abortedAt time.Time
+	err       error
+	cause     error
+}
+
+// recordAbortContext records a context created with parents if the registry is enabled
+func recordAbortContext(parents []context.Context) *abortNode {
+	r := currentAbortRegistry.Load().(*abortRegistry)
+	if r == nil {
+		return nil
+	}
+	node := &abortNode{
+		registry: r,
+		site:     abortCreationSite(),
+		created:  time.Now(),
+	}
+	// unlike propagation, the tree also looks through contexts which wrap an abortContext
+	// with their own Done, such as context.WithTimeout
+	for _, parent := range parents {
+		if p, ok := parent.Value(&abortContextKey).(*abortContext); ok && p.node != nil && p.node.registry == r {
+			node.parent = p.node.id
+			break
+		}
+	}
+	r.mu.Lock()
+	r.lastID++
+	node.id = r.lastID
+	r.nodes[node.id] = node
+	r.mu.Unlock()
+	return node
+}
+
+// abortPackage prefixes the functions of this package in stack frames
+const abortPackage = "github.com/kevindejong/futures."
+
+// abortCreationSite returns the first caller outside of this package; contexts
+// created by goroutines of this package fall back to the first caller outside
+// of the abort constructors
+func abortCreationSite() string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	var internal string
+	for {
+		frame, more := frames.Next()
+		site := fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		if !strings.HasPrefix(frame.Function, abortPackage) || strings.HasSuffix(frame.File, "_test.go") {
+			if frame.Function != "runtime.goexit" {
+				return site
+			}
+		} else if internal == "" && !strings.HasSuffix(frame.File, "/abort.go") {
+			internal = site
+		}
+		if !more {
+			return internal
+		}
+	}
+}
+
+func (n *abortNode) finish(err error, cause error) {
+	r := n.registry
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n.abortedAt = time.Now()
+	n.err = err
+	n.cause = cause
+	r.aborted.PushBack(n)
+	for r.aborted.Len() > r.keepAborted {
+		oldest := r.aborted.Remove(r.aborted.Front()).(*abortNode)
+		delete(r.nodes, oldest.id)
+	}
+}
+
+func (r *abortRegistry) tree() []*AbortNode {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	nodes := make(map[uint64]*AbortNode, len(r.nodes))
+	for id, node := range r.nodes {
+		exported := &AbortNode{
+			ID:      id,
+			Site:    node.site,
+			Created: node.created,
+			State:   abortStatePending,
+		}
+		if !node.abortedAt.IsZero() {
+			exported.State = abortStateAborted
+			exported.AbortedAt = node.abortedAt
+			if node.err != nil {
+				exported.Err = node.err.Error()
+			}
+			if node.cause != nil {
+				exported.Cause = node.cause.Error()
+			}
+		}
+		nodes[id] = exported
+	}
+	var roots []*AbortNode
+	for id, node := range r.nodes {
+		if parent, ok := nodes[node.parent]; ok {
+			parent.Children = append(parent.Children, nodes[id])
+			continue
+		}
+		roots = append(roots, nodes[id])
+	}
+	sortAbortNodes(roots)
+	return roots
+}
+
+func sortAbortNodes(nodes []*AbortNode) {
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].ID < nodes[j].ID
+	})
+	for _, node := range nodes {
+		sortAbortNodes(node.Children)
+	}
+}
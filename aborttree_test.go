@@ -0,0 +1,122 @@
+package futures
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAbortTree(t *testing.T) {
+	EnableAbortRegistry(10)
+	defer DisableAbortRegistry()
+	root, rootAbort := NewAbort()
+	defer rootAbort(nil)
+	child, childAbort := WithAbort(root)
+	defer childAbort(nil)
+	aborted, abortedFunc := WithAbort(context.WithValue(root, abortTestKey("key"), "value"))
+	WithAbort(child)
+	abortedFunc(errors.New("TestAbortTree"))
+	<-aborted.Done()
+
+	tree := AbortTree()
+	require.Len(t, tree, 1)
+	require.Equal(t, abortStatePending, tree[0].State)
+	require.True(t, strings.Contains(tree[0].Site, "aborttree_test.go"), tree[0].Site)
+	require.Len(t, tree[0].Children, 2)
+	require.Len(t, tree[0].Children[0].Children, 1)
+	require.Equal(t, abortStateAborted, tree[0].Children[1].State)
+	require.Equal(t, "TestAbortTree", tree[0].Children[1].Err)
+	require.False(t, tree[0].Children[1].AbortedAt.IsZero())
+}
+
+func TestAbortTreeKeepAborted(t *testing.T) {
+	EnableAbortRegistry(1)
+	defer DisableAbortRegistry()
+	for i := 0; i < 3; i++ {
+		_, abortFunc := NewAbort()
+		abortFunc(errors.New("TestAbortTreeKeepAborted"))
+	}
+	NewAbort()
+	tree := AbortTree()
+	require.Len(t, tree, 2)
+	require.Equal(t, uint64(3), tree[0].ID)
+	require.Equal(t, abortStatePending, tree[1].State)
+}
+
+func TestAbortTreeDisabled(t *testing.T) {
+	abortCtx, _ := NewAbort()
+	require.Nil(t, abortCtx.(*abortContext).node)
+	require.Nil(t, AbortTree())
+}
+
+func TestAbortTreeHandler(t *testing.T) {
+	EnableAbortRegistry(10)
+	defer DisableAbortRegistry()
+	root, rootAbort := NewAbort()
+	WithAbortTimeout(root, time.Minute, nil)
+	rootAbort(errors.New("TestAbortTreeHandler"))
+
+	recorder := httptest.NewRecorder()
+	AbortTreeHandler().ServeHTTP(recorder, httptest.NewRequest("GET", "/aborts", nil))
+	lines := strings.Split(strings.TrimSpace(recorder.Body.String()), "\n")
+	require.Len(t, lines, 2)
+	require.True(t, strings.HasPrefix(lines[0], "#1 aborted "), lines[0])
+	require.True(t, strings.HasSuffix(lines[0], ": TestAbortTreeHandler"), lines[0])
+	require.True(t, strings.HasPrefix(lines[1], "  #2 aborted "), lines[1])
+
+	recorder = httptest.NewRecorder()
+	AbortTreeHandler().ServeHTTP(recorder, httptest.NewRequest("GET", "/aborts?format=json", nil))
+	require.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	var tree []*AbortNode
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &tree))
+	require.Len(t, tree, 1)
+	require.Equal(t, "TestAbortTreeHandler", tree[0].Cause)
+	require.Len(t, tree[0].Children, 1)
+}
+
+func TestAbortTreeWrappedParent(t *testing.T) {
+	EnableAbortRegistry(10)
+	defer DisableAbortRegistry()
+	root, rootAbort := NewAbort()
+	defer rootAbort(nil)
+	ctx, cancel := context.WithTimeout(root, time.Minute)
+	defer cancel()
+	_, abortFunc := WithAbort(ctx)
+	defer abortFunc(nil)
+	tree := AbortTree()
+	require.Len(t, tree, 1)
+	require.Len(t, tree[0].Children, 1)
+}
+
+func TestAbortTreeSite(t *testing.T) {
+	EnableAbortRegistry(10)
+	defer DisableAbortRegistry()
+	NewScope(context.Background()).Wait()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	executor := NewExecutor(ExecutorOptions{Workers: 1})
+	defer executor.Shutdown()
+	executor.Submit(blockingTask(started, release))
+	<-started
+	defer close(release)
+	sites := make(map[string]bool)
+	var collect func(nodes []*AbortNode)
+	collect = func(nodes []*AbortNode) {
+		for _, node := range nodes {
+			sites[node.Site[strings.LastIndex(node.Site, "/")+1:strings.LastIndex(node.Site, ":")]] = true
+			collect(node.Children)
+		}
+	}
+	collect(AbortTree())
+	// the scope reports the test, while the task context created by a worker reports the executor
+	require.True(t, sites["aborttree_test.go"], sites)
+	require.True(t, sites["executor.go"], sites)
+	require.False(t, sites["scope.go"], sites)
+	require.False(t, sites["abort.go"], sites)
+}
@@ -0,0 +1,199 @@
+package futures
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned by Breaker.Execute while calls are not allowed
+var ErrCircuitOpen = errors.New("circuit open")
+
+// BreakerState is the state of a circuit breaker
+type BreakerState int
+
+const (
+	// BreakerClosed allows all calls and measures their failure rate
+	BreakerClosed BreakerState = iota
+	// BreakerOpen rejects all calls until the cool-down has passed
+	BreakerOpen
+	// BreakerHalfOpen allows a limited number of trial calls
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// BreakerTransition is published on the transitions stream of a Breaker
+type BreakerTransition struct {
+	From BreakerState
+	To   BreakerState
+	At   time.Time
+}
+
+// BreakerOptions configures when a Breaker opens and closes
+type BreakerOptions struct {
+	// Window is the period over which the failure rate is measured
+	Window time.Duration
+	// MinRequests is the number of calls in the window needed before the breaker opens
+	MinRequests int
+	// FailureRate opens the breaker once the failed fraction of calls in the window reaches it
+	FailureRate float64
+	// CoolDown is how long the breaker stays open before allowing trial calls
+	CoolDown time.Duration
+	// HalfOpenRequests is the number of successful trial calls which close the breaker; defaults to one
+	HalfOpenRequests int
+	// Clock defaults to SystemClock
+	Clock Clock
+}
+
+// Breaker fails calls fast with ErrCircuitOpen while a dependency is failing
+type Breaker struct {
+	opts        BreakerOptions
+	mu          sync.Mutex
+	state       BreakerState
+	generation  int
+	outcomes    []breakerOutcome
+	openedAt    time.Time
+	trials      int
+	successes   int
+	transitions Stream
+	sendFunc    SendFunc
+}
+
+type breakerOutcome struct {
+	at     time.Time
+	failed bool
+}
+
+// NewBreaker creates a closed breaker
+func NewBreaker(opts BreakerOptions) *Breaker {
+	if opts.HalfOpenRequests < 1 {
+		opts.HalfOpenRequests = 1
+	}
+	if opts.Clock == nil {
+		opts.Clock = SystemClock
+	}
+	transitions, sendFunc := NewStream()
+	// the base reader only serves as a source of clones, so it must not buffer items
+	transitions.Close()
+	return &Breaker{
+		opts:        opts,
+		transitions: transitions,
+		sendFunc:    sendFunc,
+	}
+}
+
+// State returns the current state of the breaker
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance(b.opts.Clock.Now())
+	return b.state
+}
+
+// Transitions returns a stream of the BreakerTransitions after this call
+func (b *Breaker) Transitions() Stream {
+	return b.transitions.Clone()
+}
+
+// Execute calls fn unless the breaker is open and records the outcome of its future;
+// a panic of fn or a nil future is recorded as a failure
+func (b *Breaker) Execute(fn func() Future) Future {
+	b.mu.Lock()
+	b.advance(b.opts.Clock.Now())
+	allowed := b.state == BreakerClosed
+	if b.state == BreakerHalfOpen && b.trials < b.opts.HalfOpenRequests {
+		b.trials++
+		allowed = true
+	}
+	generation := b.generation
+	b.mu.Unlock()
+	if !allowed {
+		future, completeFunc := NewFuture()
+		completeFunc(nil, ErrCircuitOpen)
+		return future
+	}
+	future := callFuture(fn)
+	select {
+	case <-future.Done():
+		_, err := future.Result()
+		b.record(generation, err != nil)
+	default:
+		go func() {
+			_, err := future.Result()
+			b.record(generation, err != nil)
+		}()
+	}
+	return future
+}
+
+func (b *Breaker) record(generation int, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	// outcomes of calls started in an earlier state are stale
+	if generation != b.generation {
+		return
+	}
+	now := b.opts.Clock.Now()
+	switch b.state {
+	case BreakerHalfOpen:
+		if failed {
+			b.transition(BreakerOpen, now)
+			return
+		}
+		b.successes++
+		if b.successes >= b.opts.HalfOpenRequests {
+			b.transition(BreakerClosed, now)
+		}
+	case BreakerClosed:
+		b.outcomes = append(b.outcomes, breakerOutcome{at: now, failed: failed})
+		b.prune(now)
+		failures := 0
+		for _, outcome := range b.outcomes {
+			if outcome.failed {
+				failures++
+			}
+		}
+		if len(b.outcomes) >= b.opts.MinRequests && float64(failures) >= b.opts.FailureRate*float64(len(b.outcomes)) && failures > 0 {
+			b.transition(BreakerOpen, now)
+		}
+	}
+}
+
+func (b *Breaker) prune(now time.Time) {
+	i := 0
+	for i < len(b.outcomes) && now.Sub(b.outcomes[i].at) > b.opts.Window {
+		i++
+	}
+	b.outcomes = b.outcomes[i:]
+}
+
+// advance moves an open breaker to half-open once the cool-down has passed
+func (b *Breaker) advance(now time.Time) {
+	if b.state == BreakerOpen && now.Sub(b.openedAt) >= b.opts.CoolDown {
+		b.transition(BreakerHalfOpen, now)
+	}
+}
+
+func (b *Breaker) transition(state BreakerState, now time.Time) {
+	from := b.state
+	b.state = state
+	b.generation++
+	b.outcomes = nil
+	b.trials = 0
+	b.successes = 0
+	if state == BreakerOpen {
+		b.openedAt = now
+	}
+	b.sendFunc(BreakerTransition{From: from, To: state, At: now}, nil)
+}
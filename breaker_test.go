@@ -0,0 +1,146 @@
+package futures
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func completedFuture(val interface{}, err error) func() Future {
+	return func() Future {
+		future, completeFunc := NewFuture()
+		completeFunc(val, err)
+		return future
+	}
+}
+
+func newTestBreaker() (*Breaker, *fakeClock) {
+	clock := newFakeClock()
+	return NewBreaker(BreakerOptions{
+		Window:      time.Minute,
+		MinRequests: 4,
+		FailureRate: 0.5,
+		CoolDown:    10 * time.Second,
+		Clock:       clock,
+	}), clock
+}
+
+// executeSettled executes fn, whose future is completed so its outcome is recorded immediately
+func executeSettled(t *testing.T, breaker *Breaker, fn func() Future) Future {
+	future := breaker.Execute(fn)
+	select {
+	case <-future.Done():
+	default:
+		t.Fatal("future not completed")
+	}
+	return future
+}
+
+func TestBreakerStateString(t *testing.T) {
+	require.Equal(t, "closed", BreakerClosed.String())
+	require.Equal(t, "open", BreakerOpen.String())
+	require.Equal(t, "half-open", BreakerHalfOpen.String())
+}
+
+func TestBreakerClosed(t *testing.T) {
+	breaker, _ := newTestBreaker()
+	val, err := breaker.Execute(completedFuture("TestBreakerClosed", nil)).Result()
+	require.NoError(t, err)
+	require.Equal(t, "TestBreakerClosed", val)
+	require.Equal(t, BreakerClosed, breaker.State())
+}
+
+func TestBreakerOpens(t *testing.T) {
+	breaker, _ := newTestBreaker()
+	failure := completedFuture(nil, errors.New("TestBreakerOpens"))
+	executeSettled(t, breaker, completedFuture(nil, nil))
+	executeSettled(t, breaker, failure)
+	executeSettled(t, breaker, completedFuture(nil, nil))
+	require.Equal(t, BreakerClosed, breaker.State())
+	executeSettled(t, breaker, failure)
+	require.Equal(t, BreakerOpen, breaker.State())
+	called := false
+	_, err := breaker.Execute(func() Future {
+		called = true
+		return completedFuture(nil, nil)()
+	}).Result()
+	require.Equal(t, ErrCircuitOpen, err)
+	require.False(t, called)
+}
+
+func TestBreakerWindow(t *testing.T) {
+	breaker, clock := newTestBreaker()
+	failure := completedFuture(nil, errors.New("TestBreakerWindow"))
+	executeSettled(t, breaker, failure)
+	executeSettled(t, breaker, failure)
+	clock.Advance(2 * time.Minute)
+	executeSettled(t, breaker, completedFuture(nil, nil))
+	executeSettled(t, breaker, completedFuture(nil, nil))
+	executeSettled(t, breaker, failure)
+	require.Equal(t, BreakerClosed, breaker.State())
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	breaker, clock := newTestBreaker()
+	failure := completedFuture(nil, errors.New("TestBreakerHalfOpen"))
+	for i := 0; i < 4; i++ {
+		executeSettled(t, breaker, failure)
+	}
+	require.Equal(t, BreakerOpen, breaker.State())
+	clock.Advance(10 * time.Second)
+	require.Equal(t, BreakerHalfOpen, breaker.State())
+	trial, _ := NewFuture()
+	breaker.Execute(func() Future { return trial })
+	_, err := breaker.Execute(completedFuture(nil, nil)).Result()
+	require.Equal(t, ErrCircuitOpen, err)
+}
+
+func TestBreakerHalfOpenPanic(t *testing.T) {
+	breaker, clock := newTestBreaker()
+	failure := completedFuture(nil, errors.New("TestBreakerHalfOpenPanic"))
+	for i := 0; i < 4; i++ {
+		executeSettled(t, breaker, failure)
+	}
+	clock.Advance(10 * time.Second)
+	_, err := executeSettled(t, breaker, func() Future { panic("TestBreakerHalfOpenPanic") }).Result()
+	var panicErr *PanicError
+	require.ErrorAs(t, err, &panicErr)
+	require.Equal(t, "TestBreakerHalfOpenPanic", panicErr.Value)
+	require.Equal(t, BreakerOpen, breaker.State())
+	clock.Advance(10 * time.Second)
+	_, err = executeSettled(t, breaker, func() Future { return nil }).Result()
+	require.Equal(t, ErrNilFuture, err)
+	require.Equal(t, BreakerOpen, breaker.State())
+	clock.Advance(10 * time.Second)
+	executeSettled(t, breaker, completedFuture(nil, nil))
+	require.Equal(t, BreakerClosed, breaker.State())
+}
+
+func TestBreakerTransitions(t *testing.T) {
+	breaker, clock := newTestBreaker()
+	transitions := breaker.Transitions()
+	defer transitions.Close()
+	failure := completedFuture(nil, errors.New("TestBreakerTransitions"))
+	for i := 0; i < 4; i++ {
+		executeSettled(t, breaker, failure)
+	}
+	clock.Advance(10 * time.Second)
+	executeSettled(t, breaker, failure)
+	clock.Advance(10 * time.Second)
+	executeSettled(t, breaker, completedFuture(nil, nil))
+	expected := []BreakerTransition{
+		{From: BreakerClosed, To: BreakerOpen, At: clock.Now().Add(-20 * time.Second)},
+		{From: BreakerOpen, To: BreakerHalfOpen, At: clock.Now().Add(-10 * time.Second)},
+		{From: BreakerHalfOpen, To: BreakerOpen, At: clock.Now().Add(-10 * time.Second)},
+		{From: BreakerOpen, To: BreakerHalfOpen, At: clock.Now()},
+		{From: BreakerHalfOpen, To: BreakerClosed, At: clock.Now()},
+	}
+	for _, transition := range expected {
+		item, err := transitions.Next()
+		require.NoError(t, err)
+		require.Equal(t, transition, item)
+	}
+	require.Equal(t, BreakerClosed, breaker.State())
+}
@@ -0,0 +1,260 @@
+package futures
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+)
+
+const (
+	frameItem byte = iota + 1
+	frameEnd
+	frameError
+	frameCredit
+	frameClose
+)
+
+const (
+	// bridgeWindow is the number of items a remote reader may have buffered
+	bridgeWindow = 64
+	// bridgeMaxFrame bounds the payload size accepted from the remote
+	bridgeMaxFrame = 64 << 20
+)
+
+func writeFrame(conn net.Conn, kind byte, payload []byte) error {
+	frame := make([]byte, 5+len(payload))
+	frame[0] = kind
+	binary.BigEndian.PutUint32(frame[1:5], uint32(len(payload)))
+	copy(frame[5:], payload)
+	_, err := conn.Write(frame)
+	return err
+}
+
+func readFrame(conn net.Conn) (byte, []byte, error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return 0, nil, err
+	}
+	size := binary.BigEndian.Uint32(header[1:5])
+	if size > bridgeMaxFrame {
+		return 0, nil, fmt.Errorf("frame size %d exceeds limit", size)
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(conn, payload); err != nil {
+		return 0, nil, err
+	}
+	return header[0], payload, nil
+}
+
+func creditFrame(n int) []byte {
+	payload := make([]byte, 4)
+	binary.BigEndian.PutUint32(payload, uint32(n))
+	return payload
+}
+
+// ServeStream sends the items of a stream to a DialStream reader on the other end
+// of conn. Items are only sent while the reader has granted credit, so a slow
+// reader never buffers more than a fixed window. ServeStream closes the stream
+// and conn on return; it returns nil once the stream ended or the reader closed.
+func ServeStream(conn net.Conn, stream Stream, codec Codec) error {
+	defer stream.Close()
+	server := &bridgeServer{
+		conn:  conn,
+		ready: make(chan struct{}, 1),
+		done:  make(chan struct{}),
+	}
+	go server.readCredits()
+	err := server.send(stream, codec)
+	if err == nil {
+		// wait for the reader to hang up so the terminal frame is not reset
+		<-server.done
+	}
+	conn.Close()
+	<-server.done
+	return err
+}
+
+type bridgeServer struct {
+	conn    net.Conn
+	mu      sync.Mutex
+	credits int
+	ready   chan struct{}
+	done    chan struct{}
+	err     error
+}
+
+func (b *bridgeServer) readCredits() {
+	defer close(b.done)
+	for {
+		kind, payload, err := readFrame(b.conn)
+		if err != nil {
+			b.err = err
+			return
+		}
+		switch kind {
+		case frameCredit:
+			if len(payload) != 4 {
+				b.err = errors.New("invalid credit frame")
+				return
+			}
+			b.mu.Lock()
+			b.credits += int(binary.BigEndian.Uint32(payload))
+			b.mu.Unlock()
+			select {
+			case b.ready <- struct{}{}:
+			default:
+			}
+		case frameClose:
+			return
+		default:
+			b.err = fmt.Errorf("unexpected frame type %d", kind)
+			return
+		}
+	}
+}
+
+func (b *bridgeServer) send(stream Stream, codec Codec) error {
+	for {
+		if err := b.waitCredit(); err != nil {
+			return err
+		}
+		select {
+		case <-stream.Pending():
+		case <-b.done:
+			return b.err
+		}
+		item, err := stream.Next()
+		if err != nil {
+			if err == io.EOF {
+				return b.write(frameEnd, nil)
+			}
+			return b.write(frameError, []byte(err.Error()))
+		}
+		data, err := codec.Marshal(item)
+		if err != nil {
+			b.write(frameError, []byte(err.Error()))
+			return err
+		}
+		if err := b.write(frameItem, data); err != nil {
+			return err
+		}
+		b.mu.Lock()
+		b.credits--
+		b.mu.Unlock()
+	}
+}
+
+func (b *bridgeServer) waitCredit() error {
+	for {
+		b.mu.Lock()
+		credits := b.credits
+		b.mu.Unlock()
+		if credits > 0 {
+			return nil
+		}
+		select {
+		case <-b.ready:
+		case <-b.done:
+			return b.err
+		}
+	}
+}
+
+func (b *bridgeServer) write(kind byte, payload []byte) error {
+	err := writeFrame(b.conn, kind, payload)
+	if err != nil {
+		// a reader which closed the connection is not an error
+		select {
+		case <-b.done:
+			return b.err
+		default:
+		}
+	}
+	return err
+}
+
+// DialStream reads the items sent by ServeStream on the other end of conn into a
+// stream. Credit is granted as items are consumed from the returned stream, so
+// clones do not drive flow control. Closing the returned stream closes conn.
+func DialStream(conn net.Conn, codec Codec) Stream {
+	stream, sendFunc := NewStream()
+	client := &bridgeClient{
+		Stream: stream,
+		conn:   conn,
+	}
+	go client.read(codec, sendFunc)
+	return client
+}
+
+type bridgeClient struct {
+	Stream
+	conn     net.Conn
+	mu       sync.Mutex
+	consumed int
+	once     sync.Once
+}
+
+func (b *bridgeClient) read(codec Codec, sendFunc SendFunc) {
+	defer b.conn.Close()
+	b.mu.Lock()
+	err := writeFrame(b.conn, frameCredit, creditFrame(bridgeWindow))
+	b.mu.Unlock()
+	if err != nil {
+		sendFunc(nil, err)
+		return
+	}
+	for {
+		kind, payload, err := readFrame(b.conn)
+		if err != nil {
+			sendFunc(nil, err)
+			return
+		}
+		switch kind {
+		case frameItem:
+			item, err := codec.Unmarshal(payload)
+			if err != nil {
+				sendFunc(nil, err)
+				return
+			}
+			sendFunc(item, nil)
+		case frameEnd:
+			sendFunc(nil, io.EOF)
+			return
+		case frameError:
+			sendFunc(nil, errors.New(string(payload)))
+			return
+		default:
+			sendFunc(nil, fmt.Errorf("unexpected frame type %d", kind))
+			return
+		}
+	}
+}
+
+func (b *bridgeClient) Next() (interface{}, error) {
+	item, err := b.Stream.Next()
+	if err != nil {
+		return item, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.consumed++
+	if b.consumed >= bridgeWindow/2 {
+		// write errors surface through the read loop
+		writeFrame(b.conn, frameCredit, creditFrame(b.consumed))
+		b.consumed = 0
+	}
+	return item, nil
+}
+
+func (b *bridgeClient) Close() {
+	b.Stream.Close()
+	b.once.Do(func() {
+		b.mu.Lock()
+		writeFrame(b.conn, frameClose, nil)
+		b.mu.Unlock()
+		b.conn.Close()
+	})
+}
@@ -0,0 +1,102 @@
+package futures
+
+import (
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func serveTestStream(stream Stream) (Stream, <-chan error) {
+	serverConn, clientConn := net.Pipe()
+	served := make(chan error, 1)
+	go func() {
+		served <- ServeStream(serverConn, stream, JSONCodec)
+	}()
+	return DialStream(clientConn, JSONCodec), served
+}
+
+func bufferedItems(stream Stream) int {
+	reader := stream.(*streamReader)
+	reader.RLock()
+	defer reader.RUnlock()
+	return len(reader.items)
+}
+
+func TestBridgeItems(t *testing.T) {
+	stream, sendFunc := NewStream()
+	remote, served := serveTestStream(stream)
+	defer remote.Close()
+	sendFunc("TestBridgeItems", nil)
+	sendFunc(nil, io.EOF)
+	item, err := remote.Next()
+	require.NoError(t, err)
+	require.Equal(t, "TestBridgeItems", item)
+	_, err = remote.Next()
+	require.Equal(t, io.EOF, err)
+	require.NoError(t, <-served)
+}
+
+func TestBridgeErr(t *testing.T) {
+	stream, sendFunc := NewStream()
+	remote, served := serveTestStream(stream)
+	defer remote.Close()
+	sendFunc(nil, errors.New("TestBridgeErr"))
+	_, err := remote.Next()
+	require.EqualError(t, err, "TestBridgeErr")
+	require.NoError(t, <-served)
+}
+
+func TestBridgeClose(t *testing.T) {
+	stream, _ := NewStream()
+	clone := stream.Clone()
+	remote, served := serveTestStream(clone)
+	remote.Close()
+	select {
+	case err := <-served:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("serve not returned after close")
+	}
+	_, err := clone.Next()
+	require.Equal(t, ErrStreamClosed, err)
+}
+
+func TestBridgeConnClosed(t *testing.T) {
+	serverConn, clientConn := net.Pipe()
+	remote := DialStream(clientConn, JSONCodec)
+	defer remote.Close()
+	serverConn.Close()
+	_, err := remote.Next()
+	require.Error(t, err)
+}
+
+func TestBridgeFlowControl(t *testing.T) {
+	stream, sendFunc := NewStream()
+	clone := stream.Clone()
+	remote, served := serveTestStream(clone)
+	defer remote.Close()
+	items := 3 * bridgeWindow
+	for i := 0; i < items; i++ {
+		sendFunc(i, nil)
+	}
+	local := remote.(*bridgeClient).Stream
+	require.Eventually(t, func() bool {
+		return bufferedItems(local) == bridgeWindow
+	}, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	require.Equal(t, bridgeWindow, bufferedItems(local))
+	require.Equal(t, items-bridgeWindow, bufferedItems(clone))
+	for i := 0; i < items; i++ {
+		item, err := remote.Next()
+		require.NoError(t, err)
+		require.Equal(t, float64(i), item)
+	}
+	sendFunc(nil, io.EOF)
+	_, err := remote.Next()
+	require.Equal(t, io.EOF, err)
+	require.NoError(t, <-served)
+}
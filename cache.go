@@ -0,0 +1,162 @@
+package futures
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// LoadFunc starts loading the value for a key
+type LoadFunc func(key string) Future
+
+// CacheOptions configures the expiry and size of a Cache
+type CacheOptions struct {
+	// TTL is how long a loaded value is fresh
+	TTL time.Duration
+	// RefreshAhead starts a background refresh this long before the TTL expires
+	RefreshAhead time.Duration
+	// StaleWhileRevalidate serves an expired value for this long while it is refreshed
+	StaleWhileRevalidate time.Duration
+	// MaxEntries evicts the least recently used keys beyond this size; zero is unbounded
+	MaxEntries int
+	// Clock defaults to SystemClock
+	Clock Clock
+}
+
+// Cache stores completed futures per key until their TTL expires.
+// Failed loads are never cached and a failed refresh keeps the previous value.
+type Cache struct {
+	load    LoadFunc
+	opts    CacheOptions
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+type cacheEntry struct {
+	key      string
+	future   Future
+	loadedAt time.Time
+	refresh  Future
+}
+
+// NewCache creates an empty cache which loads missing keys with load
+func NewCache(load LoadFunc, opts CacheOptions) *Cache {
+	if opts.Clock == nil {
+		opts.Clock = SystemClock
+	}
+	return &Cache{
+		load:    load,
+		opts:    opts,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// Get returns the future for key, loading or refreshing it as needed
+func (c *Cache) Get(key string) Future {
+	c.mu.Lock()
+	future, load := c.get(key)
+	c.mu.Unlock()
+	// the loader runs without the lock so that it may be slow or use the cache itself
+	if load != nil {
+		load()
+	}
+	return future
+}
+
+// get must be called with the lock held; it returns the function which starts a
+// reserved load, if any, to be called once the lock is released
+func (c *Cache) get(key string) (Future, func()) {
+	elem, ok := c.entries[key]
+	if !ok {
+		entry := &cacheEntry{
+			key: key,
+		}
+		var load func()
+		entry.future, load = c.reserve(entry)
+		c.entries[key] = c.lru.PushFront(entry)
+		c.evict()
+		return entry.future, load
+	}
+	c.lru.MoveToFront(elem)
+	entry := elem.Value.(*cacheEntry)
+	if entry.loadedAt.IsZero() {
+		return entry.future, nil
+	}
+	age := c.opts.Clock.Now().Sub(entry.loadedAt)
+	if age < c.opts.TTL-c.opts.RefreshAhead {
+		return entry.future, nil
+	}
+	var load func()
+	if entry.refresh == nil {
+		entry.refresh, load = c.reserve(entry)
+	}
+	if age < c.opts.TTL+c.opts.StaleWhileRevalidate {
+		return entry.future, load
+	}
+	return entry.refresh, load
+}
+
+// Remove drops the value for key; callers already holding its future are unaffected
+func (c *Cache) Remove(key string) {
+	c.mu.Lock()
+	if elem, ok := c.entries[key]; ok {
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+	}
+	c.mu.Unlock()
+}
+
+// Len returns the number of cached keys
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// reserve must be called with the lock held; it returns the future of a new load
+// for entry and the function which starts it
+func (c *Cache) reserve(entry *cacheEntry) (Future, func()) {
+	future, completeFunc := NewFuture()
+	return future, func() {
+		// a loader which panics or returns nil fails the load rather than leaving it pending
+		loaded := callFuture(func() Future {
+			return c.load(entry.key)
+		})
+		go func() {
+			val, err := loaded.Result()
+			completeFunc(val, err)
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			switch future {
+			case entry.refresh:
+				entry.refresh = nil
+				if err != nil {
+					return
+				}
+				entry.future = future
+				entry.loadedAt = c.opts.Clock.Now()
+			case entry.future:
+				if err != nil {
+					c.remove(entry)
+					return
+				}
+				entry.loadedAt = c.opts.Clock.Now()
+			}
+		}()
+	}
+}
+
+func (c *Cache) remove(entry *cacheEntry) {
+	if elem, ok := c.entries[entry.key]; ok && elem.Value == entry {
+		c.lru.Remove(elem)
+		delete(c.entries, entry.key)
+	}
+}
+
+func (c *Cache) evict() {
+	for c.opts.MaxEntries > 0 && c.lru.Len() > c.opts.MaxEntries {
+		c.remove(c.lru.Back().Value.(*cacheEntry))
+	}
+}
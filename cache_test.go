@@ -0,0 +1,231 @@
+package futures
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// cacheTestLoader records loads and lets the test complete them
+type cacheTestLoader struct {
+	mu        sync.Mutex
+	completes []CompleteFunc
+}
+
+func (l *cacheTestLoader) load(string) Future {
+	future, completeFunc := NewFuture()
+	l.mu.Lock()
+	l.completes = append(l.completes, completeFunc)
+	l.mu.Unlock()
+	return future
+}
+
+func (l *cacheTestLoader) loads() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.completes)
+}
+
+func (l *cacheTestLoader) complete(i int, val interface{}, err error) {
+	l.mu.Lock()
+	completeFunc := l.completes[i]
+	l.mu.Unlock()
+	completeFunc(val, err)
+}
+
+func waitCacheSettled(t *testing.T, cache *Cache, key string) {
+	require.Eventually(t, func() bool {
+		cache.mu.Lock()
+		defer cache.mu.Unlock()
+		elem, ok := cache.entries[key]
+		if !ok {
+			return true
+		}
+		entry := elem.Value.(*cacheEntry)
+		return !entry.loadedAt.IsZero() && entry.refresh == nil
+	}, time.Second, time.Millisecond)
+}
+
+func newTestCache(opts CacheOptions) (*Cache, *cacheTestLoader, *fakeClock) {
+	loader := &cacheTestLoader{}
+	clock := newFakeClock()
+	opts.Clock = clock
+	return NewCache(loader.load, opts), loader, clock
+}
+
+func TestCacheLoad(t *testing.T) {
+	cache, loader, _ := newTestCache(CacheOptions{TTL: time.Minute})
+	future := cache.Get("key")
+	require.Same(t, future, cache.Get("key"))
+	loader.complete(0, "TestCacheLoad", nil)
+	val, err := future.Result()
+	require.NoError(t, err)
+	require.Equal(t, "TestCacheLoad", val)
+	waitCacheSettled(t, cache, "key")
+	require.Same(t, future, cache.Get("key"))
+	require.Equal(t, 1, loader.loads())
+}
+
+func TestCacheLoadErr(t *testing.T) {
+	cache, loader, _ := newTestCache(CacheOptions{TTL: time.Minute})
+	future := cache.Get("key")
+	loader.complete(0, nil, errors.New("TestCacheLoadErr"))
+	<-future.Done()
+	require.Eventually(t, func() bool { return cache.Len() == 0 }, time.Second, time.Millisecond)
+	require.NotSame(t, future, cache.Get("key"))
+	require.Equal(t, 2, loader.loads())
+}
+
+func TestCacheExpired(t *testing.T) {
+	cache, loader, clock := newTestCache(CacheOptions{TTL: time.Minute})
+	future := cache.Get("key")
+	loader.complete(0, "TestCacheExpired", nil)
+	waitCacheSettled(t, cache, "key")
+	clock.Advance(time.Minute)
+	refresh := cache.Get("key")
+	require.NotSame(t, future, refresh)
+	require.Same(t, refresh, cache.Get("key"))
+	require.Equal(t, 2, loader.loads())
+}
+
+func TestCacheRefreshAhead(t *testing.T) {
+	cache, loader, clock := newTestCache(CacheOptions{TTL: time.Minute, RefreshAhead: 10 * time.Second})
+	future := cache.Get("key")
+	loader.complete(0, "TestCacheRefreshAhead1", nil)
+	waitCacheSettled(t, cache, "key")
+	clock.Advance(49 * time.Second)
+	require.Same(t, future, cache.Get("key"))
+	require.Equal(t, 1, loader.loads())
+	clock.Advance(time.Second)
+	require.Same(t, future, cache.Get("key"))
+	require.Equal(t, 2, loader.loads())
+	loader.complete(1, "TestCacheRefreshAhead2", nil)
+	waitCacheSettled(t, cache, "key")
+	val, err := cache.Get("key").Result()
+	require.NoError(t, err)
+	require.Equal(t, "TestCacheRefreshAhead2", val)
+}
+
+func TestCacheStaleWhileRevalidate(t *testing.T) {
+	cache, loader, clock := newTestCache(CacheOptions{TTL: time.Minute, StaleWhileRevalidate: time.Minute})
+	future := cache.Get("key")
+	loader.complete(0, "TestCacheStaleWhileRevalidate", nil)
+	waitCacheSettled(t, cache, "key")
+	clock.Advance(90 * time.Second)
+	require.Same(t, future, cache.Get("key"))
+	require.Same(t, future, cache.Get("key"))
+	require.Equal(t, 2, loader.loads())
+	clock.Advance(30 * time.Second)
+	refresh := cache.Get("key")
+	require.NotSame(t, future, refresh)
+	require.Equal(t, 2, loader.loads())
+}
+
+func TestCacheRefreshErr(t *testing.T) {
+	cache, loader, clock := newTestCache(CacheOptions{TTL: time.Minute, StaleWhileRevalidate: time.Minute})
+	future := cache.Get("key")
+	loader.complete(0, "TestCacheRefreshErr", nil)
+	waitCacheSettled(t, cache, "key")
+	clock.Advance(90 * time.Second)
+	cache.Get("key")
+	loader.complete(1, nil, errors.New("TestCacheRefreshErr"))
+	waitCacheSettled(t, cache, "key")
+	require.Same(t, future, cache.Get("key"))
+	require.Equal(t, 3, loader.loads())
+}
+
+func TestCacheEviction(t *testing.T) {
+	cache, _, _ := newTestCache(CacheOptions{TTL: time.Minute, MaxEntries: 2})
+	key1 := cache.Get("key1")
+	key2 := cache.Get("key2")
+	require.Same(t, key1, cache.Get("key1"))
+	cache.Get("key3")
+	require.Equal(t, 2, cache.Len())
+	require.Same(t, key1, cache.Get("key1"))
+	require.NotSame(t, key2, cache.Get("key2"))
+}
+
+func TestCacheRemove(t *testing.T) {
+	cache, _, _ := newTestCache(CacheOptions{TTL: time.Minute})
+	future := cache.Get("key")
+	cache.Remove("key")
+	require.Equal(t, 0, cache.Len())
+	require.NotSame(t, future, cache.Get("key"))
+}
+
+func TestCacheLoadWithoutLock(t *testing.T) {
+	var cache *Cache
+	cache = NewCache(func(key string) Future {
+		if key == "outer" {
+			return cache.Get("inner")
+		}
+		future, completeFunc := NewFuture()
+		completeFunc("TestCacheLoadWithoutLock", nil)
+		return future
+	}, CacheOptions{TTL: time.Minute})
+	done := make(chan struct{})
+	go func() {
+		val, err := cache.Get("outer").Result()
+		require.NoError(t, err)
+		require.Equal(t, "TestCacheLoadWithoutLock", val)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("loader calling the cache deadlocked")
+	}
+}
+
+func TestCacheLoadPanic(t *testing.T) {
+	clock := newFakeClock()
+	loads := 0
+	cache := NewCache(func(key string) Future {
+		loads++
+		switch loads {
+		case 1:
+			panic("TestCacheLoadPanic")
+		case 2:
+			return nil
+		}
+		future, completeFunc := NewFuture()
+		completeFunc("TestCacheLoadPanic", nil)
+		return future
+	}, CacheOptions{TTL: time.Minute, Clock: clock})
+	_, err := cache.Get("key").Result()
+	require.IsType(t, &PanicError{}, err)
+	waitCacheSettled(t, cache, "key")
+	_, err = cache.Get("key").Result()
+	require.Equal(t, ErrNilFuture, err)
+	waitCacheSettled(t, cache, "key")
+	val, err := cache.Get("key").Result()
+	require.NoError(t, err)
+	require.Equal(t, "TestCacheLoadPanic", val)
+	require.Equal(t, 3, loads)
+}
+
+func TestCacheRefreshPanic(t *testing.T) {
+	clock := newFakeClock()
+	loads := 0
+	cache := NewCache(func(key string) Future {
+		loads++
+		if loads == 2 {
+			panic("TestCacheRefreshPanic")
+		}
+		future, completeFunc := NewFuture()
+		completeFunc(loads, nil)
+		return future
+	}, CacheOptions{TTL: time.Minute, Clock: clock})
+	cache.Get("key").Result()
+	waitCacheSettled(t, cache, "key")
+	clock.Advance(2 * time.Minute)
+	_, err := cache.Get("key").Result()
+	require.IsType(t, &PanicError{}, err)
+	waitCacheSettled(t, cache, "key")
+	val, err := cache.Get("key").Result()
+	require.NoError(t, err)
+	require.Equal(t, 3, val)
+}
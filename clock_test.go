@@ -0,0 +1,90 @@
+package futures
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a Clock which only moves when advanced by the test
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []fakeClockWaiter
+}
+
+type fakeClockWaiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{
+		now: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, fakeClockWaiter{at: c.now.Add(d), ch: ch})
+	return ch
+}
+
+// Waiters returns the number of pending After calls
+func (c *fakeClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	waiters := c.waiters[:0]
+	for _, waiter := range c.waiters {
+		if waiter.at.After(c.now) {
+			waiters = append(waiters, waiter)
+			continue
+		}
+		waiter.ch <- c.now
+	}
+	c.waiters = waiters
+}
+
+func TestSystemClockAfter(t *testing.T) {
+	start := SystemClock.Now()
+	<-SystemClock.After(10 * time.Millisecond)
+	require.True(t, SystemClock.Now().Sub(start) >= 10*time.Millisecond)
+}
+
+func TestFakeClockAfter(t *testing.T) {
+	clock := newFakeClock()
+	after := clock.After(time.Second)
+	clock.Advance(500 * time.Millisecond)
+	select {
+	case <-after:
+		t.Fatal("fake clock fired early")
+	default:
+	}
+	clock.Advance(500 * time.Millisecond)
+	select {
+	case <-after:
+	default:
+		t.Fatal("fake clock not fired")
+	}
+}
@@ -0,0 +1,26 @@
+package futures
+
+import "encoding/json"
+
+// Codec converts items to and from bytes for transport between processes
+type Codec interface {
+	Marshal(interface{}) ([]byte, error)
+	Unmarshal([]byte) (interface{}, error)
+}
+
+// JSONCodec encodes items as JSON; items are decoded into generic JSON values
+var JSONCodec Codec = jsonCodec{}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(item interface{}) ([]byte, error) {
+	return json.Marshal(item)
+}
+
+func (jsonCodec) Unmarshal(data []byte) (interface{}, error) {
+	var item interface{}
+	if err := json.Unmarshal(data, &item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
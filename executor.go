@@ -0,0 +1,203 @@
+package futures
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrRejected is returned for tasks which do not fit in the executor queue
+	ErrRejected = errors.New("task rejected")
+	// ErrExecutorShutdown is returned for tasks submitted after shutdown or aborted by ShutdownNow
+	ErrExecutorShutdown = errors.New("executor shut down")
+)
+
+// RejectPolicy decides what happens to a task submitted to a full queue
+type RejectPolicy int
+
+const (
+	// RejectNew fails the submitted task with ErrRejected
+	RejectNew RejectPolicy = iota
+	// RejectOldest fails the oldest queued task with ErrRejected to make room
+	RejectOldest
+	// CallerRuns runs the submitted task in the submitting goroutine
+	CallerRuns
+	// Block waits in Submit until the queue has room
+	Block
+)
+
+// ExecutorOptions configures an Executor
+type ExecutorOptions struct {
+	// Workers is the number of tasks run concurrently; at least one worker is started
+	Workers int
+	// QueueSize bounds the number of waiting tasks; zero is unbounded
+	QueueSize int
+	// Reject applies when the queue is full
+	Reject RejectPolicy
+	// Order decides which queued task of a tenant runs next
+	Order QueueOrder
+	// TenantWeights shares workers between tenants in proportion to their weight;
+	// tenants without a weight have weight one
+	TenantWeights map[string]int
+}
+
+// Executor runs submitted tasks on a fixed number of worker goroutines
+type Executor struct {
+	opts     ExecutorOptions
+	ctx      AbortContext
+	abort    AbortFunc
+	mu       sync.Mutex
+	cond     *sync.Cond
+	queue    *taskQueue
+	shutdown bool
+	active   sync.WaitGroup
+	done     Future
+}
+
+type executorTask struct {
+	ctx          context.Context
+	fn           TaskFunc
+	completeFunc CompleteFunc
+	tenant       string
+	priority     int
+	deadline     time.Time
+	seq          uint64
+	index        int
+}
+
+// NewExecutor creates an executor and starts its workers
+func NewExecutor(opts ExecutorOptions) *Executor {
+	if opts.Workers < 1 {
+		opts.Workers = 1
+	}
+	ctx, abortFunc := NewAbort()
+	done, completeFunc := NewFuture()
+	e := &Executor{
+		opts:  opts,
+		ctx:   ctx,
+		abort: abortFunc,
+		queue: newTaskQueue(opts.Order, opts.TenantWeights),
+		done:  done,
+	}
+	e.cond = sync.NewCond(&e.mu)
+	e.active.Add(opts.Workers)
+	for i := 0; i < opts.Workers; i++ {
+		go e.work()
+	}
+	go func() {
+		e.active.Wait()
+		e.abort(ErrExecutorShutdown)
+		completeFunc(nil, nil)
+	}()
+	return e
+}
+
+// Submit queues fn and returns the future of its result without waiting for it to run
+func (e *Executor) Submit(fn TaskFunc) Future {
+	return e.Schedule(Task{Fn: fn})
+}
+
+// Schedule queues a task and returns the future of its result without waiting for
+// it to run. A task whose context is done or past its deadline when it would start
+// fails with the context error instead of running.
+func (e *Executor) Schedule(t Task) Future {
+	if t.Context == nil {
+		t.Context = context.Background()
+	}
+	future, completeFunc := NewFuture()
+	task := &executorTask{
+		ctx:          t.Context,
+		fn:           t.Fn,
+		completeFunc: completeFunc,
+		tenant:       t.Tenant,
+		priority:     t.Priority,
+	}
+	task.deadline, _ = t.Context.Deadline()
+	e.mu.Lock()
+	for !e.shutdown && e.full() && e.opts.Reject == Block {
+		e.cond.Wait()
+	}
+	switch {
+	case e.shutdown:
+		e.mu.Unlock()
+		completeFunc(nil, ErrExecutorShutdown)
+	case !e.full():
+		e.queue.push(task)
+		e.cond.Broadcast()
+		e.mu.Unlock()
+	case e.opts.Reject == RejectOldest:
+		oldest := e.queue.popOldest()
+		e.queue.push(task)
+		e.cond.Broadcast()
+		e.mu.Unlock()
+		oldest.completeFunc(nil, ErrRejected)
+	case e.opts.Reject == CallerRuns:
+		e.mu.Unlock()
+		e.run(task)
+	default:
+		e.mu.Unlock()
+		completeFunc(nil, ErrRejected)
+	}
+	return future
+}
+
+// Shutdown stops accepting tasks and returns a future which completes once all
+// queued and running tasks have finished
+func (e *Executor) Shutdown() Future {
+	e.mu.Lock()
+	e.shutdown = true
+	e.cond.Broadcast()
+	e.mu.Unlock()
+	return e.done
+}
+
+// ShutdownNow stops accepting tasks, fails queued tasks and aborts running tasks
+// with ErrExecutorShutdown; the future completes once running tasks have returned
+func (e *Executor) ShutdownNow() Future {
+	e.mu.Lock()
+	e.shutdown = true
+	queue := e.queue.drain()
+	e.cond.Broadcast()
+	e.mu.Unlock()
+	for _, task := range queue {
+		task.completeFunc(nil, ErrExecutorShutdown)
+	}
+	e.abort(ErrExecutorShutdown)
+	return e.done
+}
+
+func (e *Executor) full() bool {
+	return e.opts.QueueSize > 0 && e.queue.len() >= e.opts.QueueSize
+}
+
+func (e *Executor) work() {
+	defer e.active.Done()
+	for {
+		e.mu.Lock()
+		for e.queue.len() == 0 && !e.shutdown {
+			e.cond.Wait()
+		}
+		if e.queue.len() == 0 {
+			e.mu.Unlock()
+			return
+		}
+		task := e.queue.pop()
+		e.cond.Broadcast()
+		e.mu.Unlock()
+		e.run(task)
+	}
+}
+
+func (e *Executor) run(task *executorTask) {
+	if err := task.expired(time.Now()); err != nil {
+		task.completeFunc(nil, err)
+		return
+	}
+	// running tasks are also aborted by ShutdownNow
+	ctx, abortFunc := WithAbortAny(task.ctx, e.ctx)
+	val, err := runTask(ctx, task.fn)
+	abortFunc(ErrTaskDone)
+	task.completeFunc(val, err)
+}
@@ -0,0 +1,174 @@
+package futures
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// blockingTask returns a task which waits for release and records that it started
+func blockingTask(started chan<- struct{}, release <-chan struct{}) TaskFunc {
+	return func(ctx AbortContext) (interface{}, error) {
+		started <- struct{}{}
+		select {
+		case <-release:
+			return "released", nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func TestExecutorSubmit(t *testing.T) {
+	executor := NewExecutor(ExecutorOptions{Workers: 2})
+	defer executor.Shutdown()
+	future := executor.Submit(func(AbortContext) (interface{}, error) {
+		return "TestExecutorSubmit", nil
+	})
+	val, err := future.Result()
+	require.NoError(t, err)
+	require.Equal(t, "TestExecutorSubmit", val)
+}
+
+func TestExecutorTaskContextDone(t *testing.T) {
+	executor := NewExecutor(ExecutorOptions{Workers: 1})
+	defer executor.Shutdown()
+	var taskCtx AbortContext
+	_, err := executor.Submit(func(ctx AbortContext) (interface{}, error) {
+		taskCtx = ctx
+		return "TestExecutorTaskContextDone", nil
+	}).Result()
+	require.NoError(t, err)
+	<-taskCtx.Done()
+	require.Equal(t, ErrTaskDone, taskCtx.Err())
+}
+
+func TestExecutorWorkers(t *testing.T) {
+	executor := NewExecutor(ExecutorOptions{Workers: 2})
+	defer executor.Shutdown()
+	var running, peak int32
+	futures := make([]Future, 0, 10)
+	for i := 0; i < 10; i++ {
+		futures = append(futures, executor.Submit(func(AbortContext) (interface{}, error) {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			return nil, nil
+		}))
+	}
+	for _, future := range futures {
+		<-future.Done()
+	}
+	require.Equal(t, int32(2), atomic.LoadInt32(&peak))
+}
+
+func TestExecutorRejectNew(t *testing.T) {
+	executor := NewExecutor(ExecutorOptions{Workers: 1, QueueSize: 1, Reject: RejectNew})
+	defer executor.ShutdownNow()
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	executor.Submit(blockingTask(started, release))
+	<-started
+	queued := executor.Submit(blockingTask(started, release))
+	_, err := executor.Submit(blockingTask(started, release)).Result()
+	require.Equal(t, ErrRejected, err)
+	close(release)
+	_, err = queued.Result()
+	require.NoError(t, err)
+}
+
+func TestExecutorRejectOldest(t *testing.T) {
+	executor := NewExecutor(ExecutorOptions{Workers: 1, QueueSize: 1, Reject: RejectOldest})
+	defer executor.ShutdownNow()
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	executor.Submit(blockingTask(started, release))
+	<-started
+	oldest := executor.Submit(blockingTask(started, release))
+	newest := executor.Submit(func(AbortContext) (interface{}, error) {
+		return "TestExecutorRejectOldest", nil
+	})
+	_, err := oldest.Result()
+	require.Equal(t, ErrRejected, err)
+	close(release)
+	val, err := newest.Result()
+	require.NoError(t, err)
+	require.Equal(t, "TestExecutorRejectOldest", val)
+}
+
+func TestExecutorCallerRuns(t *testing.T) {
+	executor := NewExecutor(ExecutorOptions{Workers: 1, QueueSize: 1, Reject: CallerRuns})
+	defer executor.ShutdownNow()
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	executor.Submit(blockingTask(started, release))
+	<-started
+	executor.Submit(blockingTask(started, release))
+	future := executor.Submit(func(AbortContext) (interface{}, error) {
+		return "TestExecutorCallerRuns", nil
+	})
+	select {
+	case <-future.Done():
+	default:
+		t.Fatal("task not run by caller")
+	}
+	close(release)
+}
+
+func TestExecutorBlock(t *testing.T) {
+	executor := NewExecutor(ExecutorOptions{Workers: 1, QueueSize: 1, Reject: Block})
+	defer executor.ShutdownNow()
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	executor.Submit(blockingTask(started, release))
+	<-started
+	executor.Submit(blockingTask(started, release))
+	submitted := make(chan Future)
+	go func() {
+		submitted <- executor.Submit(blockingTask(started, release))
+	}()
+	select {
+	case <-submitted:
+		t.Fatal("submit not blocked by full queue")
+	case <-time.After(10 * time.Millisecond):
+	}
+	release <- struct{}{}
+	<-started
+	future := <-submitted
+	close(release)
+	_, err := future.Result()
+	require.NoError(t, err)
+}
+
+func TestExecutorShutdown(t *testing.T) {
+	executor := NewExecutor(ExecutorOptions{Workers: 1})
+	started, release := make(chan struct{}, 2), make(chan struct{})
+	running := executor.Submit(blockingTask(started, release))
+	queued := executor.Submit(blockingTask(started, release))
+	done := executor.Shutdown()
+	_, err := executor.Submit(blockingTask(started, release)).Result()
+	require.Equal(t, ErrExecutorShutdown, err)
+	close(release)
+	<-done.Done()
+	_, err = running.Result()
+	require.NoError(t, err)
+	_, err = queued.Result()
+	require.NoError(t, err)
+}
+
+func TestExecutorShutdownNow(t *testing.T) {
+	executor := NewExecutor(ExecutorOptions{Workers: 1})
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	running := executor.Submit(blockingTask(started, release))
+	<-started
+	queued := executor.Submit(blockingTask(started, release))
+	<-executor.ShutdownNow().Done()
+	_, err := running.Result()
+	require.Equal(t, ErrExecutorShutdown, err)
+	_, err = queued.Result()
+	require.Equal(t, ErrExecutorShutdown, err)
+}
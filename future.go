@@ -1,6 +1,11 @@
 package futures
 
-import "sync"
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+)
 
 // Future provides idempotent access to the result of a concurrent process
 type Future interface {
@@ -14,16 +19,18 @@ type CompleteFunc func(interface{}, error)
 // NewFuture returns a new Future along with the associated CompleteFunc
 func NewFuture() (Future, CompleteFunc) {
 	fut := &future{
-		done: make(chan struct{}),
+		done:    make(chan struct{}),
+		tracked: track(PendingFuture, 1, nil),
 	}
 	return fut, fut.complete
 }
 
 type future struct {
-	mu   sync.Mutex
-	done chan (struct{})
-	val  interface{}
-	err  error
+	mu      sync.Mutex
+	done    chan (struct{})
+	val     interface{}
+	err     error
+	tracked *trackedObject
 }
 
 // Done signals when the future has been completed
@@ -46,6 +53,57 @@ func (f *future) complete(val interface{}, err error) {
 		f.val = val
 		f.err = err
 		close(f.done)
+		if f.tracked != nil {
+			f.tracked.untrack()
+		}
 	}
 	f.mu.Unlock()
 }
+
+// TaskFunc is a unit of work which should stop once its AbortContext is done
+type TaskFunc func(AbortContext) (interface{}, error)
+
+// ErrTaskDone is the abort error of the context of a task which has returned
+var ErrTaskDone = errors.New("task done")
+
+// PanicError is the error of a task which panicked
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (p *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", p.Value)
+}
+
+// runTask calls fn and converts a panic into a PanicError
+func runTask(ctx AbortContext, fn TaskFunc) (val interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			val, err = nil, &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return fn(ctx)
+}
+
+// ErrNilFuture is the error of a call which returned a nil Future
+var ErrNilFuture = errors.New("nil future")
+
+// callFuture calls fn and converts a panic into a future failed with a PanicError
+// and a nil future into one failed with ErrNilFuture
+func callFuture(fn func() Future) (future Future) {
+	defer func() {
+		if r := recover(); r != nil {
+			var completeFunc CompleteFunc
+			future, completeFunc = NewFuture()
+			completeFunc(nil, &PanicError{Value: r, Stack: debug.Stack()})
+		}
+	}()
+	future = fn()
+	if future == nil {
+		var completeFunc CompleteFunc
+		future, completeFunc = NewFuture()
+		completeFunc(nil, ErrNilFuture)
+	}
+	return future
+}
@@ -64,3 +64,15 @@ func TestFutureResultBlocking(t *testing.T) {
 	require.EqualError(t, err, expectedErr.Error())
 	require.Equal(t, nil, val)
 }
+
+func TestRunTaskPanic(t *testing.T) {
+	ctx, _ := NewAbort()
+	val, err := runTask(ctx, func(AbortContext) (interface{}, error) {
+		panic("TestRunTaskPanic")
+	})
+	require.Equal(t, nil, val)
+	panicErr, ok := err.(*PanicError)
+	require.True(t, ok)
+	require.Equal(t, "TestRunTaskPanic", panicErr.Value)
+	require.EqualError(t, err, "panic: TestRunTaskPanic")
+}
@@ -0,0 +1,127 @@
+// Package futurestest provides assertions which fail a test that leaks futures,
+// streams or goroutines. They use the global tracking of the futures package,
+// so they must not be used by tests running in parallel.
+package futurestest
+
+import (
+	"bytes"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kevindejong/futures"
+)
+
+// SettleTimeout is how long the checks wait at cleanup for pending work to finish
+var SettleTimeout = time.Second
+
+// futuresPackage prefixes the functions of the futures package in stack traces
+const futuresPackage = "github.com/kevindejong/futures."
+
+var (
+	mu       sync.Mutex
+	trackers int
+)
+
+// VerifyNoPendingFutures fails the test if a future created after this call has
+// not been completed at cleanup
+func VerifyNoPendingFutures(t testing.TB) {
+	t.Helper()
+	verifyNoPending(t, futures.PendingFuture, "future never completed")
+}
+
+// VerifyNoOpenStreams fails the test if a stream or clone created after this call
+// has not been closed at cleanup
+func VerifyNoOpenStreams(t testing.TB) {
+	t.Helper()
+	verifyNoPending(t, futures.PendingStream, "stream never closed")
+}
+
+func verifyNoPending(t testing.TB, kind string, message string) {
+	t.Helper()
+	mu.Lock()
+	// the checks of one test share the tracking so that enabling it again does not drop records
+	if trackers == 0 {
+		futures.EnableTracking()
+	}
+	trackers++
+	mu.Unlock()
+	start := time.Now()
+	t.Cleanup(func() {
+		var pending []futures.PendingObject
+		settle(func() bool {
+			pending = pending[:0]
+			for _, object := range futures.PendingObjects(futures.PendingFilter{}) {
+				if object.Kind == kind && !object.Created.Before(start) {
+					pending = append(pending, object)
+				}
+			}
+			return len(pending) == 0
+		})
+		for _, object := range pending {
+			t.Errorf("%s, created at:\n%s", message, object.Stack)
+		}
+		mu.Lock()
+		trackers--
+		if trackers == 0 {
+			futures.DisableTracking()
+		}
+		mu.Unlock()
+	})
+}
+
+// VerifyNoLeakedGoroutines fails the test if goroutines running code of the futures
+// package, such as those started by WithAbort or the stream operators, were
+// started after this call and are still running at cleanup
+func VerifyNoLeakedGoroutines(t testing.TB) {
+	t.Helper()
+	before := goroutines()
+	t.Cleanup(func() {
+		var leaked []string
+		settle(func() bool {
+			leaked = leaked[:0]
+			for id, stack := range goroutines() {
+				if _, ok := before[id]; !ok && strings.Contains(stack, futuresPackage) {
+					leaked = append(leaked, stack)
+				}
+			}
+			return len(leaked) == 0
+		})
+		for _, stack := range leaked {
+			t.Errorf("goroutine leaked:\n%s", stack)
+		}
+	})
+}
+
+// goroutines returns the stacks of all goroutines by their header id
+func goroutines() map[string]string {
+	buf := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	stacks := make(map[string]string)
+	for _, stack := range bytes.Split(buf, []byte("\n\n")) {
+		// the header is "goroutine <id> [<state>]:"
+		fields := strings.Fields(string(stack))
+		if len(fields) < 2 {
+			continue
+		}
+		stacks[fields[1]] = string(stack)
+	}
+	return stacks
+}
+
+// settle polls done until it returns true or the SettleTimeout passes
+func settle(done func() bool) {
+	deadline := time.Now().Add(SettleTimeout)
+	for !done() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+}
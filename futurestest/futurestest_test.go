@@ -0,0 +1,102 @@
+package futurestest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kevindejong/futures"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder is a testing.TB which records errors and runs its cleanups on demand
+type recorder struct {
+	testing.TB
+	cleanups []func()
+	errors   []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Cleanup(f func()) {
+	r.cleanups = append(r.cleanups, f)
+}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) finish() {
+	for i := len(r.cleanups) - 1; i >= 0; i-- {
+		r.cleanups[i]()
+	}
+}
+
+func init() {
+	SettleTimeout = 50 * time.Millisecond
+}
+
+func TestVerifyNoPendingFutures(t *testing.T) {
+	r := &recorder{TB: t}
+	VerifyNoPendingFutures(r)
+	_, completeFunc := futures.NewFuture()
+	completeFunc(nil, nil)
+	futures.NewFuture()
+	r.finish()
+	require.Len(t, r.errors, 1)
+	require.Contains(t, r.errors[0], "future never completed")
+	require.Contains(t, r.errors[0], "TestVerifyNoPendingFutures")
+	require.Nil(t, futures.PendingObjects(futures.PendingFilter{}))
+}
+
+func TestVerifyNoOpenStreams(t *testing.T) {
+	r := &recorder{TB: t}
+	VerifyNoPendingFutures(r)
+	VerifyNoOpenStreams(r)
+	stream, _ := futures.NewStream()
+	stream.Close()
+	stream.Clone()
+	r.finish()
+	require.Len(t, r.errors, 1)
+	require.Contains(t, r.errors[0], "stream never closed")
+}
+
+func TestVerifyNoLeakedGoroutines(t *testing.T) {
+	r := &recorder{TB: t}
+	VerifyNoLeakedGoroutines(r)
+	stream, sendFunc := futures.NewStream()
+	done := make(chan struct{})
+	go func() {
+		stream.Next()
+		close(done)
+	}()
+	require.Eventually(t, func() bool {
+		for _, stack := range goroutines() {
+			if strings.Contains(stack, "streamReader).Next") {
+				return true
+			}
+		}
+		return false
+	}, time.Second, time.Millisecond)
+	r.finish()
+	require.Len(t, r.errors, 1)
+	require.Contains(t, r.errors[0], "goroutine leaked")
+	sendFunc(nil, context.Canceled)
+	<-done
+}
+
+func TestVerifyNoLeakedGoroutinesClean(t *testing.T) {
+	r := &recorder{TB: t}
+	VerifyNoLeakedGoroutines(r)
+	VerifyNoPendingFutures(r)
+	VerifyNoOpenStreams(r)
+	scope := futures.NewScope(context.Background())
+	scope.Go(func(futures.AbortContext) (interface{}, error) {
+		return nil, nil
+	})
+	scope.Wait().Result()
+	r.finish()
+	require.Empty(t, r.errors)
+}
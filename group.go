@@ -0,0 +1,116 @@
+package futures
+
+import (
+	"context"
+	"sync"
+)
+
+// FailurePolicy decides whether a Group memoizes failed results
+type FailurePolicy int
+
+const (
+	// ForgetFailures drops failed results so the next call starts a new load
+	ForgetFailures FailurePolicy = iota
+	// KeepFailures memoizes failed results like successful ones
+	KeepFailures
+)
+
+// Group memoizes loads by key so that concurrent callers share one future
+type Group struct {
+	policy FailurePolicy
+	mu     sync.Mutex
+	calls  map[string]*groupCall
+}
+
+type groupCall struct {
+	future  Future
+	abort   AbortFunc
+	waiters int
+}
+
+// NewGroup creates an empty group with the given failure policy
+func NewGroup(policy FailurePolicy) *Group {
+	return &Group{
+		policy: policy,
+		calls:  make(map[string]*groupCall),
+	}
+}
+
+// Do returns the future for key, starting fn unless a load is in flight or memoized.
+// The load is aborted with the last error once the contexts of all callers are done.
+func (g *Group) Do(ctx context.Context, key string, fn TaskFunc) Future {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	call, ok := g.calls[key]
+	if !ok {
+		call = g.start(key, fn)
+	}
+	select {
+	case <-call.future.Done():
+		return call.future
+	default:
+	}
+	call.waiters++
+	if ctx.Done() != nil {
+		go func() {
+			select {
+			case <-ctx.Done():
+				g.leave(key, call, ctx.Err())
+			case <-call.future.Done():
+			}
+		}()
+	}
+	return call.future
+}
+
+func (g *Group) start(key string, fn TaskFunc) *groupCall {
+	abortCtx, abortFunc := NewAbort()
+	future, completeFunc := NewFuture()
+	call := &groupCall{
+		future: future,
+		abort:  abortFunc,
+	}
+	g.calls[key] = call
+	go func() {
+		val, err := runTask(abortCtx, fn)
+		if err != nil && g.policy == ForgetFailures {
+			g.forget(key, call)
+		}
+		completeFunc(val, err)
+	}()
+	return call
+}
+
+func (g *Group) leave(key string, call *groupCall, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	call.waiters--
+	if call.waiters > 0 {
+		return
+	}
+	select {
+	case <-call.future.Done():
+		return
+	default:
+	}
+	// an abandoned load is never memoized regardless of the policy
+	if g.calls[key] == call {
+		delete(g.calls, key)
+	}
+	call.abort(err)
+}
+
+func (g *Group) forget(key string, call *groupCall) {
+	g.mu.Lock()
+	if g.calls[key] == call {
+		delete(g.calls, key)
+	}
+	g.mu.Unlock()
+}
+
+// Forget drops the load for key; callers already holding its future are unaffected
+func (g *Group) Forget(key string) {
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+}
@@ -0,0 +1,117 @@
+package futures
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupShared(t *testing.T) {
+	group := NewGroup(ForgetFailures)
+	var calls int32
+	release := make(chan struct{})
+	fn := func(AbortContext) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "TestGroupShared", nil
+	}
+	future1 := group.Do(context.Background(), "key", fn)
+	future2 := group.Do(context.Background(), "key", fn)
+	require.Same(t, future1, future2)
+	close(release)
+	val, err := future1.Result()
+	require.NoError(t, err)
+	require.Equal(t, "TestGroupShared", val)
+	require.Same(t, future1, group.Do(context.Background(), "key", fn))
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestGroupKeys(t *testing.T) {
+	group := NewGroup(ForgetFailures)
+	fn := func(AbortContext) (interface{}, error) {
+		return nil, nil
+	}
+	require.NotSame(t, group.Do(context.Background(), "key1", fn), group.Do(context.Background(), "key2", fn))
+}
+
+func TestGroupForgetFailures(t *testing.T) {
+	group := NewGroup(ForgetFailures)
+	expectedErr := errors.New("TestGroupForgetFailures")
+	fn := func(AbortContext) (interface{}, error) {
+		return nil, expectedErr
+	}
+	future := group.Do(context.Background(), "key", fn)
+	_, err := future.Result()
+	require.Equal(t, expectedErr, err)
+	require.NotSame(t, future, group.Do(context.Background(), "key", fn))
+}
+
+func TestGroupKeepFailures(t *testing.T) {
+	group := NewGroup(KeepFailures)
+	expectedErr := errors.New("TestGroupKeepFailures")
+	fn := func(AbortContext) (interface{}, error) {
+		return nil, expectedErr
+	}
+	future := group.Do(context.Background(), "key", fn)
+	_, err := future.Result()
+	require.Equal(t, expectedErr, err)
+	require.Same(t, future, group.Do(context.Background(), "key", fn))
+}
+
+func TestGroupForget(t *testing.T) {
+	group := NewGroup(ForgetFailures)
+	fn := func(AbortContext) (interface{}, error) {
+		return "TestGroupForget", nil
+	}
+	future := group.Do(context.Background(), "key", fn)
+	<-future.Done()
+	group.Forget("key")
+	require.NotSame(t, future, group.Do(context.Background(), "key", fn))
+}
+
+func TestGroupAbortAllWaiters(t *testing.T) {
+	group := NewGroup(ForgetFailures)
+	fn := func(ctx AbortContext) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	future := group.Do(ctx1, "key", fn)
+	group.Do(ctx2, "key", fn)
+	cancel1()
+	select {
+	case <-future.Done():
+		t.Fatal("load aborted with a remaining waiter")
+	case <-time.After(10 * time.Millisecond):
+	}
+	cancel2()
+	_, err := future.Result()
+	require.Equal(t, context.Canceled, err)
+}
+
+func TestGroupAbortNotMemoized(t *testing.T) {
+	group := NewGroup(KeepFailures)
+	fn := func(ctx AbortContext) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	future := group.Do(ctx, "key", fn)
+	cancel()
+	<-future.Done()
+	require.NotSame(t, future, group.Do(context.Background(), "key", fn))
+}
+
+func TestGroupPanic(t *testing.T) {
+	group := NewGroup(ForgetFailures)
+	_, err := group.Do(context.Background(), "key", func(AbortContext) (interface{}, error) {
+		panic("TestGroupPanic")
+	}).Result()
+	require.IsType(t, &PanicError{}, err)
+	require.Equal(t, "TestGroupPanic", err.(*PanicError).Value)
+}
@@ -0,0 +1,95 @@
+package futures
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrHedgeLost aborts the attempts of a hedged request which did not win
+var ErrHedgeLost = errors.New("hedged attempt lost")
+
+// HedgeResult is the value of a hedged future
+type HedgeResult struct {
+	Value interface{}
+	// Attempt is the winning attempt, numbered from one
+	Attempt int
+}
+
+type hedgeAttempt struct {
+	attempt int
+	val     interface{}
+	err     error
+}
+
+// Hedge runs fn and starts another attempt whenever the latest has not completed
+// within delay or has failed, up to maxAttempts. The future completes with the
+// first success as a HedgeResult and the remaining attempts are aborted with
+// ErrHedgeLost. If every attempt fails the future fails with an AttemptsError, and
+// once ctx is done no further attempt is started and it fails with the error of ctx.
+func Hedge(ctx context.Context, delay time.Duration, maxAttempts int, fn TaskFunc) Future {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	future, completeFunc := NewFuture()
+	go func() {
+		completeFunc(hedge(ctx, delay, maxAttempts, fn))
+	}()
+	return future
+}
+
+func hedge(ctx context.Context, delay time.Duration, maxAttempts int, fn TaskFunc) (interface{}, error) {
+	results := make(chan hedgeAttempt, maxAttempts)
+	aborts := make([]AbortFunc, 0, maxAttempts)
+	defer func() {
+		for _, abortFunc := range aborts {
+			abortFunc(ErrHedgeLost)
+		}
+	}()
+	var timer *time.Timer
+	var next <-chan time.Time
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+	start := func() {
+		attempt := len(aborts) + 1
+		attemptCtx, abortFunc := WithAbort(ctx)
+		aborts = append(aborts, abortFunc)
+		go func() {
+			val, err := runTask(attemptCtx, fn)
+			results <- hedgeAttempt{attempt: attempt, val: val, err: err}
+		}()
+		if timer != nil {
+			timer.Stop()
+		}
+		timer, next = nil, nil
+		if len(aborts) < maxAttempts {
+			timer = time.NewTimer(delay)
+			next = timer.C
+		}
+	}
+	start()
+	attempts := &AttemptsError{}
+	for len(attempts.Errors) < len(aborts) {
+		select {
+		case result := <-results:
+			if result.err == nil {
+				return HedgeResult{Value: result.val, Attempt: result.attempt}, nil
+			}
+			attempts.Errors = append(attempts.Errors, result.err)
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			if len(aborts) < maxAttempts {
+				start()
+			}
+		case <-next:
+			start()
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return nil, attempts
+}
@@ -0,0 +1,86 @@
+package futures
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHedgeFirstAttempt(t *testing.T) {
+	var calls int32
+	val, err := Hedge(context.Background(), time.Minute, 3, func(AbortContext) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return "TestHedgeFirstAttempt", nil
+	}).Result()
+	require.NoError(t, err)
+	require.Equal(t, HedgeResult{Value: "TestHedgeFirstAttempt", Attempt: 1}, val)
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestHedgeBackupWins(t *testing.T) {
+	var calls int32
+	lost := make(chan error, 1)
+	val, err := Hedge(context.Background(), 5*time.Millisecond, 3, func(ctx AbortContext) (interface{}, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			<-ctx.Done()
+			lost <- ctx.Err()
+			return nil, ctx.Err()
+		}
+		return "TestHedgeBackupWins", nil
+	}).Result()
+	require.NoError(t, err)
+	require.Equal(t, HedgeResult{Value: "TestHedgeBackupWins", Attempt: 2}, val)
+	require.Equal(t, ErrHedgeLost, <-lost)
+}
+
+func TestHedgeFailureStartsNext(t *testing.T) {
+	var calls int32
+	val, err := Hedge(context.Background(), time.Minute, 3, func(AbortContext) (interface{}, error) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			return nil, errors.New("TestHedgeFailureStartsNext")
+		}
+		return "TestHedgeFailureStartsNext", nil
+	}).Result()
+	require.NoError(t, err)
+	require.Equal(t, HedgeResult{Value: "TestHedgeFailureStartsNext", Attempt: 3}, val)
+}
+
+func TestHedgeAllFail(t *testing.T) {
+	expectedErr := errors.New("TestHedgeAllFail")
+	var calls int32
+	_, err := Hedge(context.Background(), time.Millisecond, 3, func(AbortContext) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, expectedErr
+	}).Result()
+	require.Len(t, err.(*AttemptsError).Errors, 3)
+	require.True(t, errors.Is(err, expectedErr))
+	require.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestHedgeCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	future := Hedge(ctx, time.Millisecond, 2, func(ctx AbortContext) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	time.Sleep(5 * time.Millisecond)
+	cancel()
+	_, err := future.Result()
+	require.Equal(t, context.Canceled, err)
+}
+
+func TestHedgeCancelStopsAttempts(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls int32
+	_, err := Hedge(ctx, time.Minute, 5, func(AbortContext) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		cancel()
+		return nil, errors.New("TestHedgeCancelStopsAttempts")
+	}).Result()
+	require.Equal(t, context.Canceled, err)
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
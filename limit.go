@@ -0,0 +1,273 @@
+package futures
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter grants capacity through a future which fails with the context error
+// if the context is done before capacity is available
+type Limiter interface {
+	Acquire(ctx context.Context) Future
+}
+
+// ReleaseFunc returns capacity acquired from a ConcurrencyLimiter
+type ReleaseFunc func()
+
+type limiterWaiter struct {
+	completeFunc CompleteFunc
+}
+
+// waiterQueue tracks the futures waiting for capacity in arrival order
+type waiterQueue struct {
+	waiters *list.List
+}
+
+func (q *waiterQueue) push(ctx context.Context, mu *sync.Mutex) Future {
+	future, completeFunc := NewFuture()
+	elem := q.waiters.PushBack(&limiterWaiter{completeFunc: completeFunc})
+	if ctx.Done() != nil {
+		go func() {
+			select {
+			case <-ctx.Done():
+				mu.Lock()
+				// the waiter is only removed by the goroutine which completes it
+				if elem.Value != nil {
+					q.waiters.Remove(elem)
+					elem.Value = nil
+					mu.Unlock()
+					completeFunc(nil, ctx.Err())
+					return
+				}
+				mu.Unlock()
+			case <-future.Done():
+			}
+		}()
+	}
+	return future
+}
+
+// pop must be called with the lock held and the queue not empty
+func (q *waiterQueue) pop() *limiterWaiter {
+	elem := q.waiters.Front()
+	waiter := elem.Value.(*limiterWaiter)
+	q.waiters.Remove(elem)
+	elem.Value = nil
+	return waiter
+}
+
+// RateLimiterOptions configures the rate of a RateLimiter
+type RateLimiterOptions struct {
+	// Every is the interval at which a token is added
+	Every time.Duration
+	// Burst is the number of tokens the bucket holds; defaults to one
+	Burst int
+	// Clock defaults to SystemClock
+	Clock Clock
+}
+
+// RateLimiter is a token bucket Limiter which adds a token every interval
+type RateLimiter struct {
+	mu      sync.Mutex
+	opts    RateLimiterOptions
+	tokens  float64
+	last    time.Time
+	queue   waiterQueue
+	pending bool
+}
+
+// NewRateLimiter creates a full token bucket
+func NewRateLimiter(opts RateLimiterOptions) *RateLimiter {
+	if opts.Burst < 1 {
+		opts.Burst = 1
+	}
+	if opts.Clock == nil {
+		opts.Clock = SystemClock
+	}
+	return &RateLimiter{
+		opts:   opts,
+		tokens: float64(opts.Burst),
+		last:   opts.Clock.Now(),
+		queue:  waiterQueue{waiters: list.New()},
+	}
+}
+
+// Acquire returns a future which completes once a token has been taken
+func (r *RateLimiter) Acquire(ctx context.Context) Future {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.refill(r.opts.Clock.Now())
+	if r.queue.waiters.Len() == 0 && r.tokens >= 1 {
+		r.tokens--
+		future, completeFunc := NewFuture()
+		completeFunc(nil, nil)
+		return future
+	}
+	future := r.queue.push(ctx, &r.mu)
+	r.schedule()
+	return future
+}
+
+func (r *RateLimiter) refill(now time.Time) {
+	if r.opts.Every <= 0 {
+		r.tokens = float64(r.opts.Burst)
+		return
+	}
+	r.tokens += float64(now.Sub(r.last)) / float64(r.opts.Every)
+	if r.tokens > float64(r.opts.Burst) {
+		r.tokens = float64(r.opts.Burst)
+	}
+	r.last = now
+}
+
+// schedule must be called with the lock held while waiters are queued
+func (r *RateLimiter) schedule() {
+	if r.pending {
+		return
+	}
+	r.pending = true
+	wait := time.Duration((1 - r.tokens) * float64(r.opts.Every))
+	expired := r.opts.Clock.After(wait)
+	go func() {
+		<-expired
+		r.grant()
+	}()
+}
+
+func (r *RateLimiter) grant() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pending = false
+	r.refill(r.opts.Clock.Now())
+	for r.queue.waiters.Len() > 0 && r.tokens >= 1 {
+		r.tokens--
+		r.queue.pop().completeFunc(nil, nil)
+	}
+	if r.queue.waiters.Len() > 0 {
+		r.schedule()
+	}
+}
+
+// ConcurrencyLimiter is a semaphore Limiter; the value of an acquired future is a
+// ReleaseFunc which must be called once the capacity is no longer used
+type ConcurrencyLimiter struct {
+	mu        sync.Mutex
+	available int
+	queue     waiterQueue
+}
+
+// NewConcurrencyLimiter creates a limiter allowing n concurrent holders
+func NewConcurrencyLimiter(n int) *ConcurrencyLimiter {
+	return &ConcurrencyLimiter{
+		available: n,
+		queue:     waiterQueue{waiters: list.New()},
+	}
+}
+
+// Acquire returns a future which completes with a ReleaseFunc once capacity is available
+func (c *ConcurrencyLimiter) Acquire(ctx context.Context) Future {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.queue.waiters.Len() == 0 && c.available > 0 {
+		c.available--
+		future, completeFunc := NewFuture()
+		completeFunc(c.releaseFunc(), nil)
+		return future
+	}
+	return c.queue.push(ctx, &c.mu)
+}
+
+func (c *ConcurrencyLimiter) releaseFunc() ReleaseFunc {
+	var once sync.Once
+	return func() {
+		once.Do(c.release)
+	}
+}
+
+func (c *ConcurrencyLimiter) release() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.queue.waiters.Len() > 0 {
+		c.queue.pop().completeFunc(c.releaseFunc(), nil)
+		return
+	}
+	c.available++
+}
+
+// Limit returns a stream whose Next calls are paced to one item every interval
+// after an initial burst. Clones are paced independently. Pending reserves the
+// token of the next item, so it only fires once Next can return without waiting.
+func Limit(stream Stream, opts RateLimiterOptions) Stream {
+	ctx, abortFunc := NewAbort()
+	return &limitedStream{
+		Stream:  stream,
+		limiter: NewRateLimiter(opts),
+		opts:    opts,
+		ctx:     ctx,
+		abort:   abortFunc,
+	}
+}
+
+type limitedStream struct {
+	Stream
+	limiter *RateLimiter
+	opts    RateLimiterOptions
+	ctx     AbortContext
+	abort   AbortFunc
+	mu      sync.Mutex
+	token   Future
+	ready   chan struct{}
+}
+
+// reserve returns the token of the next item, acquiring it if needed; the caller must hold mu
+func (l *limitedStream) reserve() Future {
+	if l.token == nil {
+		l.token = l.limiter.Acquire(l.ctx)
+	}
+	return l.token
+}
+
+func (l *limitedStream) Pending() <-chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.ready != nil {
+		return l.ready
+	}
+	token := l.reserve()
+	pending := l.Stream.Pending()
+	l.ready = make(chan struct{})
+	go func(ready chan struct{}) {
+		<-token.Done()
+		// a closed stream aborts ctx, which also ends the wait for an item that never comes
+		select {
+		case <-pending:
+		case <-l.ctx.Done():
+		}
+		close(ready)
+	}(l.ready)
+	return l.ready
+}
+
+func (l *limitedStream) Next() (interface{}, error) {
+	// the reserved token is claimed at once so that concurrent calls do not share it
+	l.mu.Lock()
+	token := l.reserve()
+	l.token = nil
+	l.ready = nil
+	l.mu.Unlock()
+	if _, err := token.Result(); err != nil {
+		return nil, err
+	}
+	return l.Stream.Next()
+}
+
+func (l *limitedStream) Clone() Stream {
+	return Limit(l.Stream.Clone(), l.opts)
+}
+
+func (l *limitedStream) Close() {
+	l.abort(ErrStreamClosed)
+	l.Stream.Close()
+}
@@ -0,0 +1,175 @@
+package futures
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func requireCompleted(t *testing.T, future Future) {
+	select {
+	case <-future.Done():
+	default:
+		t.Fatal("future not completed")
+	}
+}
+
+func requirePending(t *testing.T, future Future) {
+	select {
+	case <-future.Done():
+		t.Fatal("future unexpectedly completed")
+	default:
+	}
+}
+
+func TestRateLimiterBurst(t *testing.T) {
+	limiter := NewRateLimiter(RateLimiterOptions{Every: time.Minute, Burst: 2})
+	requireCompleted(t, limiter.Acquire(context.Background()))
+	requireCompleted(t, limiter.Acquire(context.Background()))
+	requirePending(t, limiter.Acquire(context.Background()))
+}
+
+func TestRateLimiterRefill(t *testing.T) {
+	clock := newFakeClock()
+	limiter := NewRateLimiter(RateLimiterOptions{Every: time.Second, Clock: clock})
+	requireCompleted(t, limiter.Acquire(context.Background()))
+	future := limiter.Acquire(context.Background())
+	requirePending(t, future)
+	clock.Advance(999 * time.Millisecond)
+	requirePending(t, future)
+	clock.Advance(time.Millisecond)
+	_, err := future.Result()
+	require.NoError(t, err)
+}
+
+func TestRateLimiterAbort(t *testing.T) {
+	limiter := NewRateLimiter(RateLimiterOptions{Every: time.Minute})
+	limiter.Acquire(context.Background())
+	ctx, abortFunc := NewAbort()
+	future := limiter.Acquire(ctx)
+	expectedErr := errors.New("TestRateLimiterAbort")
+	abortFunc(expectedErr)
+	_, err := future.Result()
+	require.Equal(t, expectedErr, err)
+	require.Equal(t, 0, limiter.queue.waiters.Len())
+}
+
+func TestConcurrencyLimiter(t *testing.T) {
+	limiter := NewConcurrencyLimiter(1)
+	first := limiter.Acquire(context.Background())
+	requireCompleted(t, first)
+	second := limiter.Acquire(context.Background())
+	requirePending(t, second)
+	release, err := first.Result()
+	require.NoError(t, err)
+	release.(ReleaseFunc)()
+	release.(ReleaseFunc)()
+	requireCompleted(t, second)
+	requirePending(t, limiter.Acquire(context.Background()))
+}
+
+func TestConcurrencyLimiterAbort(t *testing.T) {
+	limiter := NewConcurrencyLimiter(1)
+	first, _ := limiter.Acquire(context.Background()).Result()
+	ctx, abortFunc := NewAbort()
+	future := limiter.Acquire(ctx)
+	expectedErr := errors.New("TestConcurrencyLimiterAbort")
+	abortFunc(expectedErr)
+	_, err := future.Result()
+	require.Equal(t, expectedErr, err)
+	first.(ReleaseFunc)()
+	requireCompleted(t, limiter.Acquire(context.Background()))
+}
+
+func TestLimitStream(t *testing.T) {
+	clock := newFakeClock()
+	stream, sendFunc := NewStream()
+	limited := Limit(stream, RateLimiterOptions{Every: time.Second, Clock: clock})
+	defer limited.Close()
+	sendFunc("TestLimitStream1", nil)
+	sendFunc("TestLimitStream2", nil)
+	item, err := limited.Next()
+	require.NoError(t, err)
+	require.Equal(t, "TestLimitStream1", item)
+	done := make(chan interface{})
+	go func() {
+		item, _ := limited.Next()
+		done <- item
+	}()
+	require.Eventually(t, func() bool { return clock.Waiters() == 1 }, time.Second, time.Millisecond)
+	select {
+	case <-done:
+		t.Fatal("stream not paced")
+	default:
+	}
+	clock.Advance(time.Second)
+	require.Equal(t, "TestLimitStream2", <-done)
+}
+
+func TestLimitStreamClose(t *testing.T) {
+	stream, sendFunc := NewStream()
+	limited := Limit(stream, RateLimiterOptions{Every: time.Minute})
+	sendFunc("TestLimitStreamClose", nil)
+	limited.Next()
+	done := make(chan error)
+	go func() {
+		_, err := limited.Next()
+		done <- err
+	}()
+	time.Sleep(5 * time.Millisecond)
+	limited.Close()
+	require.Equal(t, ErrStreamClosed, <-done)
+}
+
+func TestLimitStreamPending(t *testing.T) {
+	clock := newFakeClock()
+	stream, sendFunc := NewStream()
+	limited := Limit(stream, RateLimiterOptions{Every: time.Second, Clock: clock})
+	defer limited.Close()
+	sendFunc("TestLimitStreamPending1", nil)
+	sendFunc("TestLimitStreamPending2", nil)
+	<-limited.Pending()
+	item, err := limited.Next()
+	require.NoError(t, err)
+	require.Equal(t, "TestLimitStreamPending1", item)
+	// an item is available but the token is not, so Pending waits for the clock
+	pending := limited.Pending()
+	require.Eventually(t, func() bool { return clock.Waiters() == 1 }, time.Second, time.Millisecond)
+	select {
+	case <-pending:
+		t.Fatal("pending fired before a token was available")
+	default:
+	}
+	clock.Advance(time.Second)
+	<-pending
+	item, err = limited.Next()
+	require.NoError(t, err)
+	require.Equal(t, "TestLimitStreamPending2", item)
+	// a token is available but no item, so Pending waits for the stream
+	clock.Advance(time.Second)
+	pending = limited.Pending()
+	time.Sleep(5 * time.Millisecond)
+	select {
+	case <-pending:
+		t.Fatal("pending fired before an item was sent")
+	default:
+	}
+	sendFunc("TestLimitStreamPending3", nil)
+	<-pending
+	item, err = limited.Next()
+	require.NoError(t, err)
+	require.Equal(t, "TestLimitStreamPending3", item)
+}
+
+func TestLimitStreamPendingClose(t *testing.T) {
+	stream, _ := NewStream()
+	limited := Limit(stream, RateLimiterOptions{Every: time.Minute})
+	pending := limited.Pending()
+	limited.Close()
+	<-pending
+	_, err := limited.Next()
+	require.Equal(t, ErrStreamClosed, err)
+}
@@ -0,0 +1,174 @@
+package futures
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrFutureNotFound is returned when no future is registered under an ID
+var ErrFutureNotFound = errors.New("future not found")
+
+const (
+	remotePathPrefix    = "/futures/"
+	remoteRetryDelay    = 50 * time.Millisecond
+	remoteMaxRetryDelay = 5 * time.Second
+)
+
+// FutureServer is an http.Handler which exposes registered futures at
+// GET /futures/{id}; requests wait up to the poll timeout for completion
+type FutureServer struct {
+	codec       Codec
+	pollTimeout time.Duration
+	mu          sync.RWMutex
+	futures     map[string]Future
+}
+
+type remoteResult struct {
+	Value []byte `json:"value,omitempty"`
+	Err   string `json:"error,omitempty"`
+}
+
+// NewFutureServer creates a server which encodes results with the codec
+func NewFutureServer(codec Codec, pollTimeout time.Duration) *FutureServer {
+	return &FutureServer{
+		codec:       codec,
+		pollTimeout: pollTimeout,
+		futures:     make(map[string]Future),
+	}
+}
+
+// Register exposes a future under an ID, replacing any future with the same ID
+func (s *FutureServer) Register(id string, future Future) {
+	s.mu.Lock()
+	s.futures[id] = future
+	s.mu.Unlock()
+}
+
+// Unregister removes the future registered under an ID
+func (s *FutureServer) Unregister(id string) {
+	s.mu.Lock()
+	delete(s.futures, id)
+	s.mu.Unlock()
+}
+
+func (s *FutureServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !strings.HasPrefix(r.URL.Path, remotePathPrefix) {
+		http.NotFound(w, r)
+		return
+	}
+	s.mu.RLock()
+	future, ok := s.futures[strings.TrimPrefix(r.URL.Path, remotePathPrefix)]
+	s.mu.RUnlock()
+	if !ok {
+		http.Error(w, ErrFutureNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	timer := time.NewTimer(s.pollTimeout)
+	defer timer.Stop()
+	select {
+	case <-future.Done():
+	case <-timer.C:
+		w.WriteHeader(http.StatusAccepted)
+		return
+	case <-r.Context().Done():
+		return
+	}
+	result := remoteResult{}
+	val, err := future.Result()
+	if err != nil {
+		result.Err = err.Error()
+	} else if result.Value, err = s.codec.Marshal(val); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// RemoteFuture returns a future which long-polls the FutureServer url until the
+// remote future completes. Failed requests are retried with backoff and the
+// future fails with ctx.Err() once ctx is done.
+func RemoteFuture(ctx context.Context, client *http.Client, url string, codec Codec) Future {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	future, completeFunc := NewFuture()
+	go func() {
+		completeFunc(pollRemote(ctx, client, url, codec))
+	}()
+	return future
+}
+
+func pollRemote(ctx context.Context, client *http.Client, url string, codec Codec) (interface{}, error) {
+	delay := remoteRetryDelay
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		sent := time.Now()
+		resp, err := client.Do(req)
+		if err == nil {
+			result := remoteResult{}
+			switch {
+			case resp.StatusCode == http.StatusOK:
+				err = json.NewDecoder(resp.Body).Decode(&result)
+				resp.Body.Close()
+				if err != nil {
+					return nil, err
+				}
+				if result.Err != "" {
+					return nil, errors.New(result.Err)
+				}
+				return codec.Unmarshal(result.Value)
+			case resp.StatusCode == http.StatusAccepted:
+				resp.Body.Close()
+				delay = remoteRetryDelay
+				// a poll answered early, such as by a server without a poll timeout, must not
+				// turn into a tight loop
+				if err := sleepContext(ctx, remoteRetryDelay-time.Since(sent)); err != nil {
+					return nil, err
+				}
+				continue
+			case resp.StatusCode == http.StatusNotFound:
+				resp.Body.Close()
+				return nil, ErrFutureNotFound
+			case resp.StatusCode < http.StatusInternalServerError:
+				resp.Body.Close()
+				return nil, fmt.Errorf("unexpected status %s", resp.Status)
+			}
+			resp.Body.Close()
+		}
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil, err
+		}
+		if delay *= 2; delay > remoteMaxRetryDelay {
+			delay = remoteMaxRetryDelay
+		}
+	}
+}
+
+// sleepContext waits for d, failing with ctx.Err() if ctx is done first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
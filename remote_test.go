@@ -0,0 +1,134 @@
+package futures
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFutureServerResult(t *testing.T) {
+	server := NewFutureServer(JSONCodec, time.Second)
+	future, complete := NewFuture()
+	server.Register("id", future)
+	complete("TestFutureServerResult", nil)
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/futures/id", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.JSONEq(t, `{"value":"IlRlc3RGdXR1cmVTZXJ2ZXJSZXN1bHQi"}`, recorder.Body.String())
+}
+
+func TestFutureServerPending(t *testing.T) {
+	server := NewFutureServer(JSONCodec, 10*time.Millisecond)
+	future, _ := NewFuture()
+	server.Register("id", future)
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/futures/id", nil))
+	require.Equal(t, http.StatusAccepted, recorder.Code)
+}
+
+func TestFutureServerNotFound(t *testing.T) {
+	server := NewFutureServer(JSONCodec, time.Second)
+	future, _ := NewFuture()
+	server.Register("id", future)
+	server.Unregister("id")
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/futures/id", nil))
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestRemoteFutureResult(t *testing.T) {
+	futureServer := NewFutureServer(JSONCodec, 10*time.Millisecond)
+	future, complete := NewFuture()
+	futureServer.Register("id", future)
+	server := httptest.NewServer(futureServer)
+	defer server.Close()
+	remote := RemoteFuture(context.Background(), server.Client(), server.URL+"/futures/id", JSONCodec)
+	select {
+	case <-remote.Done():
+		t.Fatal("remote future unexpectedly completed")
+	case <-time.After(30 * time.Millisecond):
+	}
+	complete("TestRemoteFutureResult", nil)
+	val, err := remote.Result()
+	require.NoError(t, err)
+	require.Equal(t, "TestRemoteFutureResult", val)
+}
+
+func TestRemoteFuturePollDelay(t *testing.T) {
+	futureServer := NewFutureServer(JSONCodec, 0)
+	future, complete := NewFuture()
+	futureServer.Register("id", future)
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		futureServer.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	remote := RemoteFuture(context.Background(), server.Client(), server.URL+"/futures/id", JSONCodec)
+	time.Sleep(5 * remoteRetryDelay / 2)
+	// without a delay between polls answered at once this would be thousands of requests
+	require.LessOrEqual(t, atomic.LoadInt32(&requests), int32(4))
+	complete("TestRemoteFuturePollDelay", nil)
+	val, err := remote.Result()
+	require.NoError(t, err)
+	require.Equal(t, "TestRemoteFuturePollDelay", val)
+}
+
+func TestRemoteFutureErr(t *testing.T) {
+	futureServer := NewFutureServer(JSONCodec, time.Second)
+	future, complete := NewFuture()
+	futureServer.Register("id", future)
+	complete(nil, errors.New("TestRemoteFutureErr"))
+	server := httptest.NewServer(futureServer)
+	defer server.Close()
+	val, err := RemoteFuture(context.Background(), server.Client(), server.URL+"/futures/id", JSONCodec).Result()
+	require.EqualError(t, err, "TestRemoteFutureErr")
+	require.Equal(t, nil, val)
+}
+
+func TestRemoteFutureNotFound(t *testing.T) {
+	server := httptest.NewServer(NewFutureServer(JSONCodec, time.Second))
+	defer server.Close()
+	_, err := RemoteFuture(context.Background(), server.Client(), server.URL+"/futures/id", JSONCodec).Result()
+	require.Equal(t, ErrFutureNotFound, err)
+}
+
+func TestRemoteFutureRetry(t *testing.T) {
+	futureServer := NewFutureServer(JSONCodec, time.Second)
+	future, complete := NewFuture()
+	futureServer.Register("id", future)
+	complete("TestRemoteFutureRetry", nil)
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		futureServer.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	val, err := RemoteFuture(context.Background(), server.Client(), server.URL+"/futures/id", JSONCodec).Result()
+	require.NoError(t, err)
+	require.Equal(t, "TestRemoteFutureRetry", val)
+	require.Equal(t, 2, requests)
+}
+
+func TestRemoteFutureCancel(t *testing.T) {
+	futureServer := NewFutureServer(JSONCodec, time.Second)
+	future, _ := NewFuture()
+	futureServer.Register("id", future)
+	server := httptest.NewServer(futureServer)
+	defer server.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	remote := RemoteFuture(ctx, server.Client(), server.URL+"/futures/id", JSONCodec)
+	cancel()
+	_, err := remote.Result()
+	require.Equal(t, context.Canceled, err)
+}
@@ -0,0 +1,122 @@
+package futures
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// Backoff returns the delay before a retry; retries are numbered from one
+type Backoff func(retry int) time.Duration
+
+// ConstantBackoff waits the same delay before every retry
+func ConstantBackoff(delay time.Duration) Backoff {
+	return func(int) time.Duration {
+		return delay
+	}
+}
+
+// ExponentialBackoff doubles the delay after every retry, starting at base and capped at max
+func ExponentialBackoff(base, max time.Duration) Backoff {
+	return func(retry int) time.Duration {
+		delay := base
+		for i := 1; i < retry && delay < max; i++ {
+			delay *= 2
+		}
+		if delay > max {
+			delay = max
+		}
+		return delay
+	}
+}
+
+// JitteredBackoff waits a random delay up to the ExponentialBackoff delay
+func JitteredBackoff(base, max time.Duration) Backoff {
+	exponential := ExponentialBackoff(base, max)
+	return func(retry int) time.Duration {
+		delay := exponential(retry)
+		if delay <= 0 {
+			return 0
+		}
+		return time.Duration(rand.Int63n(int64(delay) + 1))
+	}
+}
+
+// RetryPolicy decides whether and when a failed attempt is retried
+type RetryPolicy struct {
+	// Backoff defaults to retrying immediately
+	Backoff Backoff
+	// MaxAttempts bounds the number of attempts; zero is unlimited
+	MaxAttempts int
+	// MaxElapsed stops retrying once the next attempt would start after this long; zero is unlimited
+	MaxElapsed time.Duration
+	// Retryable classifies errors; by default every error is retryable
+	Retryable func(error) bool
+	// Clock defaults to SystemClock
+	Clock Clock
+}
+
+// AttemptsError is the error of an operation which failed every attempt
+type AttemptsError struct {
+	Errors []error
+}
+
+func (a *AttemptsError) Error() string {
+	return fmt.Sprintf("%d attempts failed, last error: %v", len(a.Errors), a.Errors[len(a.Errors)-1])
+}
+
+// Unwrap allows errors.Is and errors.As to match the error of the last attempt
+func (a *AttemptsError) Unwrap() error {
+	return a.Errors[len(a.Errors)-1]
+}
+
+// Retry runs fn until it succeeds or the policy gives up, in which case the future
+// fails with an AttemptsError. Once ctx is done no further attempt is started and
+// the future fails with the error of ctx.
+func Retry(ctx context.Context, policy RetryPolicy, fn TaskFunc) Future {
+	if policy.Backoff == nil {
+		policy.Backoff = ConstantBackoff(0)
+	}
+	if policy.Retryable == nil {
+		policy.Retryable = func(error) bool { return true }
+	}
+	if policy.Clock == nil {
+		policy.Clock = SystemClock
+	}
+	future, completeFunc := NewFuture()
+	go func() {
+		abortCtx, abortFunc := WithAbort(ctx)
+		completeFunc(retry(ctx, abortCtx, policy, fn))
+		abortFunc(ErrTaskDone)
+	}()
+	return future
+}
+
+// retry checks ctx rather than attemptCtx, which may not have seen the end of ctx yet
+func retry(ctx context.Context, attemptCtx AbortContext, policy RetryPolicy, fn TaskFunc) (interface{}, error) {
+	start := policy.Clock.Now()
+	attempts := &AttemptsError{}
+	for attempt := 1; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		val, err := runTask(attemptCtx, fn)
+		if err == nil {
+			return val, nil
+		}
+		attempts.Errors = append(attempts.Errors, err)
+		if !policy.Retryable(err) || (policy.MaxAttempts > 0 && attempt >= policy.MaxAttempts) {
+			return nil, attempts
+		}
+		delay := policy.Backoff(attempt)
+		if policy.MaxElapsed > 0 && policy.Clock.Now().Add(delay).Sub(start) > policy.MaxElapsed {
+			return nil, attempts
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-policy.Clock.After(delay):
+		}
+	}
+}
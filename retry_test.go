@@ -0,0 +1,158 @@
+package futures
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// failingTask fails with err until it has been called n times
+func failingTask(n int32, err error) (TaskFunc, *int32) {
+	var calls int32
+	return func(AbortContext) (interface{}, error) {
+		if atomic.AddInt32(&calls, 1) < n {
+			return nil, err
+		}
+		return "success", nil
+	}, &calls
+}
+
+func TestConstantBackoff(t *testing.T) {
+	backoff := ConstantBackoff(time.Second)
+	require.Equal(t, time.Second, backoff(1))
+	require.Equal(t, time.Second, backoff(10))
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := ExponentialBackoff(time.Second, 5*time.Second)
+	require.Equal(t, time.Second, backoff(1))
+	require.Equal(t, 2*time.Second, backoff(2))
+	require.Equal(t, 4*time.Second, backoff(3))
+	require.Equal(t, 5*time.Second, backoff(4))
+	require.Equal(t, 5*time.Second, backoff(100))
+}
+
+func TestJitteredBackoff(t *testing.T) {
+	backoff := JitteredBackoff(time.Second, 5*time.Second)
+	for i := 1; i < 10; i++ {
+		delay := backoff(i)
+		require.True(t, delay >= 0 && delay <= ExponentialBackoff(time.Second, 5*time.Second)(i))
+	}
+}
+
+func TestRetrySuccess(t *testing.T) {
+	fn, calls := failingTask(3, errors.New("TestRetrySuccess"))
+	val, err := Retry(context.Background(), RetryPolicy{}, fn).Result()
+	require.NoError(t, err)
+	require.Equal(t, "success", val)
+	require.Equal(t, int32(3), atomic.LoadInt32(calls))
+}
+
+func TestRetryContextDone(t *testing.T) {
+	var attemptCtx AbortContext
+	_, err := Retry(context.Background(), RetryPolicy{MaxAttempts: 1}, func(ctx AbortContext) (interface{}, error) {
+		attemptCtx = ctx
+		return "TestRetryContextDone", nil
+	}).Result()
+	require.NoError(t, err)
+	<-attemptCtx.Done()
+	require.Equal(t, ErrTaskDone, attemptCtx.Err())
+}
+
+func TestRetryMaxAttempts(t *testing.T) {
+	expectedErr := errors.New("TestRetryMaxAttempts")
+	fn, calls := failingTask(10, expectedErr)
+	_, err := Retry(context.Background(), RetryPolicy{MaxAttempts: 3}, fn).Result()
+	attempts, ok := err.(*AttemptsError)
+	require.True(t, ok)
+	require.Equal(t, []error{expectedErr, expectedErr, expectedErr}, attempts.Errors)
+	require.True(t, errors.Is(err, expectedErr))
+	require.Equal(t, int32(3), atomic.LoadInt32(calls))
+}
+
+func TestRetryNotRetryable(t *testing.T) {
+	expectedErr := errors.New("TestRetryNotRetryable")
+	fn, calls := failingTask(10, expectedErr)
+	policy := RetryPolicy{
+		Retryable: func(err error) bool { return err != expectedErr },
+	}
+	_, err := Retry(context.Background(), policy, fn).Result()
+	require.True(t, errors.Is(err, expectedErr))
+	require.Equal(t, int32(1), atomic.LoadInt32(calls))
+}
+
+func TestRetryBackoffClock(t *testing.T) {
+	clock := newFakeClock()
+	fn, calls := failingTask(3, errors.New("TestRetryBackoffClock"))
+	policy := RetryPolicy{
+		Backoff: ExponentialBackoff(time.Second, time.Minute),
+		Clock:   clock,
+	}
+	future := Retry(context.Background(), policy, fn)
+	require.Eventually(t, func() bool { return clock.Waiters() == 1 }, time.Second, time.Millisecond)
+	require.Equal(t, int32(1), atomic.LoadInt32(calls))
+	clock.Advance(time.Second)
+	require.Eventually(t, func() bool { return clock.Waiters() == 1 }, time.Second, time.Millisecond)
+	require.Equal(t, int32(2), atomic.LoadInt32(calls))
+	clock.Advance(time.Second)
+	select {
+	case <-future.Done():
+		t.Fatal("retry did not wait for backoff")
+	case <-time.After(10 * time.Millisecond):
+	}
+	clock.Advance(time.Second)
+	val, err := future.Result()
+	require.NoError(t, err)
+	require.Equal(t, "success", val)
+}
+
+func TestRetryMaxElapsed(t *testing.T) {
+	clock := newFakeClock()
+	fn, calls := failingTask(10, errors.New("TestRetryMaxElapsed"))
+	policy := RetryPolicy{
+		Backoff:    ConstantBackoff(time.Second),
+		MaxElapsed: 1500 * time.Millisecond,
+		Clock:      clock,
+	}
+	future := Retry(context.Background(), policy, fn)
+	require.Eventually(t, func() bool { return clock.Waiters() == 1 }, time.Second, time.Millisecond)
+	clock.Advance(time.Second)
+	_, err := future.Result()
+	require.Len(t, err.(*AttemptsError).Errors, 2)
+	require.Equal(t, int32(2), atomic.LoadInt32(calls))
+}
+
+func TestRetryAbort(t *testing.T) {
+	ctx, abortFunc := NewAbort()
+	expectedErr := errors.New("TestRetryAbort")
+	fn, _ := failingTask(10, errors.New("TestRetryAbort attempt"))
+	future := Retry(ctx, RetryPolicy{Backoff: ConstantBackoff(time.Minute)}, fn)
+	abortFunc(expectedErr)
+	_, err := future.Result()
+	require.Equal(t, expectedErr, err)
+}
+
+func TestRetryAbortDuringAttempt(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls int32
+	_, err := Retry(ctx, RetryPolicy{MaxAttempts: 1000}, func(AbortContext) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		cancel()
+		return nil, errors.New("TestRetryAbortDuringAttempt")
+	}).Result()
+	require.Equal(t, context.Canceled, err)
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestRetryAbortBeforeAttempt(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	fn, calls := failingTask(1, nil)
+	_, err := Retry(ctx, RetryPolicy{}, fn).Result()
+	require.Equal(t, context.Canceled, err)
+	require.Equal(t, int32(0), atomic.LoadInt32(calls))
+}
@@ -0,0 +1,207 @@
+package futures
+
+import (
+	"container/heap"
+	"context"
+	"time"
+)
+
+// QueueOrder decides which queued task of a tenant runs next
+type QueueOrder int
+
+const (
+	// FIFO runs tasks in submission order
+	FIFO QueueOrder = iota
+	// HighestPriority runs the task with the highest Priority first
+	HighestPriority
+	// EarliestDeadline runs the task whose context deadline is earliest first;
+	// tasks without a deadline run after those with one
+	EarliestDeadline
+)
+
+// Task describes a unit of work scheduled on an Executor
+type Task struct {
+	// Context is the parent of the task AbortContext and defaults to context.Background()
+	Context context.Context
+	// Priority orders HighestPriority queues; higher values run first
+	Priority int
+	// Tenant selects the weighted queue the task is scheduled on
+	Tenant string
+	// Fn is run with the task AbortContext
+	Fn TaskFunc
+}
+
+// taskQueue holds a heap per tenant and picks tenants by smooth weighted round robin;
+// only tenants with queued tasks are kept
+type taskQueue struct {
+	order   QueueOrder
+	weights map[string]int
+	tenants map[string]*tenantQueue
+	names   []string
+	size    int
+	seq     uint64
+}
+
+type tenantQueue struct {
+	name    string
+	tasks   taskHeap
+	weight  int
+	current int
+}
+
+func newTaskQueue(order QueueOrder, weights map[string]int) *taskQueue {
+	return &taskQueue{
+		order:   order,
+		weights: weights,
+		tenants: make(map[string]*tenantQueue),
+	}
+}
+
+func (q *taskQueue) len() int {
+	return q.size
+}
+
+func (q *taskQueue) push(task *executorTask) {
+	tenant, ok := q.tenants[task.tenant]
+	if !ok {
+		weight := q.weights[task.tenant]
+		if weight < 1 {
+			weight = 1
+		}
+		tenant = &tenantQueue{
+			name:   task.tenant,
+			tasks:  taskHeap{order: q.order},
+			weight: weight,
+		}
+		q.tenants[task.tenant] = tenant
+		q.names = append(q.names, task.tenant)
+	}
+	task.seq = q.seq
+	q.seq++
+	heap.Push(&tenant.tasks, task)
+	q.size++
+}
+
+func (q *taskQueue) pop() *executorTask {
+	var next *tenantQueue
+	total := 0
+	for _, name := range q.names {
+		tenant := q.tenants[name]
+		tenant.current += tenant.weight
+		total += tenant.weight
+		if next == nil || tenant.current > next.current {
+			next = tenant
+		}
+	}
+	if next == nil {
+		return nil
+	}
+	next.current -= total
+	q.size--
+	task := heap.Pop(&next.tasks).(*executorTask)
+	if next.tasks.Len() == 0 {
+		// idle tenants are dropped, so they do not accumulate credit
+		q.remove(next)
+	}
+	return task
+}
+
+func (q *taskQueue) remove(tenant *tenantQueue) {
+	delete(q.tenants, tenant.name)
+	for i, name := range q.names {
+		if name == tenant.name {
+			q.names = append(q.names[:i], q.names[i+1:]...)
+			return
+		}
+	}
+}
+
+// popOldest removes the task which was queued first across all tenants
+func (q *taskQueue) popOldest() *executorTask {
+	var oldest *executorTask
+	for _, tenant := range q.tenants {
+		for _, task := range tenant.tasks.tasks {
+			if oldest == nil || task.seq < oldest.seq {
+				oldest = task
+			}
+		}
+	}
+	if oldest == nil {
+		return nil
+	}
+	tenant := q.tenants[oldest.tenant]
+	heap.Remove(&tenant.tasks, oldest.index)
+	if tenant.tasks.Len() == 0 {
+		q.remove(tenant)
+	}
+	q.size--
+	return oldest
+}
+
+func (q *taskQueue) drain() []*executorTask {
+	tasks := make([]*executorTask, 0, q.size)
+	for _, tenant := range q.tenants {
+		tasks = append(tasks, tenant.tasks.tasks...)
+	}
+	q.tenants = make(map[string]*tenantQueue)
+	q.names = nil
+	q.size = 0
+	return tasks
+}
+
+type taskHeap struct {
+	order QueueOrder
+	tasks []*executorTask
+}
+
+func (h taskHeap) Len() int {
+	return len(h.tasks)
+}
+
+func (h taskHeap) Less(i, j int) bool {
+	a, b := h.tasks[i], h.tasks[j]
+	switch h.order {
+	case HighestPriority:
+		if a.priority != b.priority {
+			return a.priority > b.priority
+		}
+	case EarliestDeadline:
+		if !a.deadline.Equal(b.deadline) {
+			if a.deadline.IsZero() || b.deadline.IsZero() {
+				return b.deadline.IsZero()
+			}
+			return a.deadline.Before(b.deadline)
+		}
+	}
+	return a.seq < b.seq
+}
+
+func (h taskHeap) Swap(i, j int) {
+	h.tasks[i], h.tasks[j] = h.tasks[j], h.tasks[i]
+	h.tasks[i].index = i
+	h.tasks[j].index = j
+}
+
+func (h *taskHeap) Push(x interface{}) {
+	task := x.(*executorTask)
+	task.index = len(h.tasks)
+	h.tasks = append(h.tasks, task)
+}
+
+func (h *taskHeap) Pop() interface{} {
+	task := h.tasks[len(h.tasks)-1]
+	h.tasks[len(h.tasks)-1] = nil
+	h.tasks = h.tasks[:len(h.tasks)-1]
+	return task
+}
+
+// expired returns the error of a task whose context ended before it started
+func (t *executorTask) expired(now time.Time) error {
+	if err := t.ctx.Err(); err != nil {
+		return err
+	}
+	if deadline, ok := t.ctx.Deadline(); ok && !now.Before(deadline) {
+		return context.DeadlineExceeded
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package futures
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// scheduleOrder blocks a single worker, schedules tasks and returns the order they ran in
+func scheduleOrder(t *testing.T, opts ExecutorOptions, tasks []Task) []string {
+	opts.Workers = 1
+	executor := NewExecutor(opts)
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	executor.Submit(blockingTask(started, release))
+	<-started
+	var mu sync.Mutex
+	order := []string{}
+	futures := []Future{}
+	for _, task := range tasks {
+		name := task.Fn
+		task.Fn = func(ctx AbortContext) (interface{}, error) {
+			val, err := name(ctx)
+			mu.Lock()
+			order = append(order, val.(string))
+			mu.Unlock()
+			return val, err
+		}
+		futures = append(futures, executor.Schedule(task))
+	}
+	close(release)
+	for _, future := range futures {
+		<-future.Done()
+	}
+	<-executor.Shutdown().Done()
+	return order
+}
+
+func namedTask(name string) TaskFunc {
+	return func(AbortContext) (interface{}, error) {
+		return name, nil
+	}
+}
+
+func TestScheduleFIFO(t *testing.T) {
+	order := scheduleOrder(t, ExecutorOptions{}, []Task{
+		{Fn: namedTask("a"), Priority: 1},
+		{Fn: namedTask("b"), Priority: 2},
+		{Fn: namedTask("c"), Priority: 3},
+	})
+	require.Equal(t, []string{"a", "b", "c"}, order)
+}
+
+func TestScheduleHighestPriority(t *testing.T) {
+	order := scheduleOrder(t, ExecutorOptions{Order: HighestPriority}, []Task{
+		{Fn: namedTask("a"), Priority: 1},
+		{Fn: namedTask("b"), Priority: 3},
+		{Fn: namedTask("c"), Priority: 2},
+		{Fn: namedTask("d"), Priority: 3},
+	})
+	require.Equal(t, []string{"b", "d", "c", "a"}, order)
+}
+
+func TestScheduleEarliestDeadline(t *testing.T) {
+	now := time.Now()
+	ctx1, cancel1 := context.WithDeadline(context.Background(), now.Add(3*time.Minute))
+	defer cancel1()
+	ctx2, cancel2 := context.WithDeadline(context.Background(), now.Add(time.Minute))
+	defer cancel2()
+	order := scheduleOrder(t, ExecutorOptions{Order: EarliestDeadline}, []Task{
+		{Fn: namedTask("a")},
+		{Fn: namedTask("b"), Context: ctx1},
+		{Fn: namedTask("c"), Context: ctx2},
+	})
+	require.Equal(t, []string{"c", "b", "a"}, order)
+}
+
+func TestScheduleTenantWeights(t *testing.T) {
+	tasks := []Task{}
+	for i := 0; i < 4; i++ {
+		tasks = append(tasks, Task{Fn: namedTask("a"), Tenant: "a"})
+	}
+	for i := 0; i < 4; i++ {
+		tasks = append(tasks, Task{Fn: namedTask("b"), Tenant: "b"})
+	}
+	order := scheduleOrder(t, ExecutorOptions{TenantWeights: map[string]int{"a": 3}}, tasks)
+	require.Equal(t, []string{"a", "a", "b", "a", "a", "b", "b", "b"}, order)
+}
+
+func TestScheduleDeadlinePassed(t *testing.T) {
+	executor := NewExecutor(ExecutorOptions{Workers: 1})
+	defer executor.Shutdown()
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	executor.Submit(blockingTask(started, release))
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	ran := false
+	future := executor.Schedule(Task{
+		Context: ctx,
+		Fn: func(AbortContext) (interface{}, error) {
+			ran = true
+			return nil, nil
+		},
+	})
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	_, err := future.Result()
+	require.Equal(t, context.DeadlineExceeded, err)
+	require.False(t, ran)
+}
+
+func TestScheduleAbortDeadlinePassed(t *testing.T) {
+	executor := NewExecutor(ExecutorOptions{Workers: 1})
+	defer executor.Shutdown()
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	executor.Submit(blockingTask(started, release))
+	<-started
+	deadlineErr := errors.New("TestScheduleAbortDeadlinePassed")
+	ctx, abortFunc := WithAbortTimeout(context.Background(), 5*time.Millisecond, deadlineErr)
+	defer abortFunc(nil)
+	ran := false
+	future := executor.Schedule(Task{
+		Context: ctx,
+		Fn: func(AbortContext) (interface{}, error) {
+			ran = true
+			return nil, nil
+		},
+	})
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	_, err := future.Result()
+	require.Equal(t, deadlineErr, err)
+	require.False(t, ran)
+}
+
+func TestScheduleTaskContext(t *testing.T) {
+	executor := NewExecutor(ExecutorOptions{Workers: 1})
+	defer executor.Shutdown()
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{}, 1)
+	future := executor.Schedule(Task{
+		Context: ctx,
+		Fn:      blockingTask(started, nil),
+	})
+	<-started
+	cancel()
+	_, err := future.Result()
+	require.Equal(t, context.Canceled, err)
+}
+
+func TestScheduleTenantsRemoved(t *testing.T) {
+	queue := newTaskQueue(FIFO, nil)
+	queue.push(&executorTask{tenant: "a"})
+	queue.push(&executorTask{tenant: "b"})
+	queue.push(&executorTask{tenant: "b"})
+	require.Equal(t, "a", queue.pop().tenant)
+	require.Equal(t, []string{"b"}, queue.names)
+	require.Len(t, queue.tenants, 1)
+	require.NotNil(t, queue.popOldest())
+	require.NotNil(t, queue.pop())
+	require.Empty(t, queue.names)
+	require.Empty(t, queue.tenants)
+	queue.push(&executorTask{tenant: "c"})
+	require.Len(t, queue.drain(), 1)
+	require.Empty(t, queue.tenants)
+}
@@ -0,0 +1,98 @@
+package futures
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrScopeClosed is returned for tasks started on a scope after Wait
+var ErrScopeClosed = errors.New("scope closed")
+
+// Scope runs child tasks under a shared AbortContext which the first failing
+// child aborts with its error, so that no child outlives the scope
+type Scope struct {
+	ctx          AbortContext
+	abort        AbortFunc
+	mu           sync.Mutex
+	pending      int
+	closed       bool
+	finished     bool
+	err          error
+	done         Future
+	completeFunc CompleteFunc
+}
+
+// NewScope creates a scope whose AbortContext wraps ctx
+func NewScope(ctx context.Context) *Scope {
+	abortCtx, abortFunc := WithAbort(ctx)
+	done, completeFunc := NewFuture()
+	return &Scope{
+		ctx:          abortCtx,
+		abort:        abortFunc,
+		done:         done,
+		completeFunc: completeFunc,
+	}
+}
+
+// Context returns the AbortContext shared by the children of the scope
+func (s *Scope) Context() AbortContext {
+	return s.ctx
+}
+
+// Go runs fn in a new goroutine and returns the future of its result
+func (s *Scope) Go(fn TaskFunc) Future {
+	future, completeFunc := NewFuture()
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		completeFunc(nil, ErrScopeClosed)
+		return future
+	}
+	s.pending++
+	s.mu.Unlock()
+	go func() {
+		val, err := runTask(s.ctx, fn)
+		if err != nil {
+			s.fail(err)
+		}
+		completeFunc(val, err)
+		s.finish(-1)
+	}()
+	return future
+}
+
+// Wait closes the scope to new tasks and returns a future which completes with
+// the first child error once every child has finished
+func (s *Scope) Wait() Future {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	s.finish(0)
+	return s.done
+}
+
+func (s *Scope) fail(err error) {
+	s.mu.Lock()
+	if s.err == nil {
+		s.err = err
+	}
+	s.mu.Unlock()
+	s.abort(err)
+}
+
+// finish completes the scope once it is closed and all children have finished
+func (s *Scope) finish(delta int) {
+	s.mu.Lock()
+	s.pending += delta
+	finished := s.closed && s.pending == 0 && !s.finished
+	if finished {
+		s.finished = true
+	}
+	err := s.err
+	s.mu.Unlock()
+	if finished {
+		s.abort(ErrScopeClosed)
+		s.completeFunc(nil, err)
+	}
+}
@@ -0,0 +1,95 @@
+package futures
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScopeResults(t *testing.T) {
+	scope := NewScope(context.Background())
+	future := scope.Go(func(AbortContext) (interface{}, error) {
+		return "TestScopeResults", nil
+	})
+	_, err := scope.Wait().Result()
+	require.NoError(t, err)
+	val, err := future.Result()
+	require.NoError(t, err)
+	require.Equal(t, "TestScopeResults", val)
+}
+
+func TestScopeWaitEmpty(t *testing.T) {
+	scope := NewScope(context.Background())
+	_, err := scope.Wait().Result()
+	require.NoError(t, err)
+	require.Equal(t, ErrScopeClosed, scope.Context().Err())
+}
+
+func TestScopeFirstErr(t *testing.T) {
+	scope := NewScope(context.Background())
+	expectedErr := errors.New("TestScopeFirstErr")
+	blocked := scope.Go(func(ctx AbortContext) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	scope.Go(func(AbortContext) (interface{}, error) {
+		return nil, expectedErr
+	})
+	_, err := scope.Wait().Result()
+	require.Equal(t, expectedErr, err)
+	_, err = blocked.Result()
+	require.Equal(t, expectedErr, err)
+	require.Equal(t, expectedErr, scope.Context().Err())
+}
+
+func TestScopePanic(t *testing.T) {
+	scope := NewScope(context.Background())
+	scope.Go(func(AbortContext) (interface{}, error) {
+		panic("TestScopePanic")
+	})
+	_, err := scope.Wait().Result()
+	require.IsType(t, &PanicError{}, err)
+}
+
+func TestScopeNoOutlive(t *testing.T) {
+	scope := NewScope(context.Background())
+	var running int32
+	for i := 0; i < 10; i++ {
+		delay := time.Duration(i) * time.Millisecond
+		scope.Go(func(AbortContext) (interface{}, error) {
+			atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			time.Sleep(delay)
+			return nil, nil
+		})
+	}
+	scope.Wait().Result()
+	require.Equal(t, int32(0), atomic.LoadInt32(&running))
+}
+
+func TestScopeGoAfterWait(t *testing.T) {
+	scope := NewScope(context.Background())
+	scope.Wait()
+	_, err := scope.Go(func(AbortContext) (interface{}, error) {
+		return nil, nil
+	}).Result()
+	require.Equal(t, ErrScopeClosed, err)
+}
+
+func TestScopeParentCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	scope := NewScope(ctx)
+	future := scope.Go(func(ctx AbortContext) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	cancel()
+	_, err := future.Result()
+	require.Equal(t, context.Canceled, err)
+	_, err = scope.Wait().Result()
+	require.Equal(t, context.Canceled, err)
+}
@@ -0,0 +1,175 @@
+package futures
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrGraceExpired is the error of the hard context once the grace period has passed
+	ErrGraceExpired = errors.New("shutdown grace period expired")
+	// ErrShutdownStarted is returned when registering a hook after Start
+	ErrShutdownStarted = errors.New("shutdown started")
+	// ErrDuplicateHook is returned when registering a hook name twice
+	ErrDuplicateHook = errors.New("duplicate shutdown hook")
+	// ErrUnknownHook is returned when a hook depends on a hook which is not registered
+	ErrUnknownHook = errors.New("unknown shutdown hook")
+)
+
+// ShutdownHook stops one component; Fn is called with the hard context once the
+// hooks named in After have completed, and its future reports when it is stopped
+type ShutdownHook struct {
+	Name  string
+	After []string
+	Fn    func(ctx AbortContext) Future
+}
+
+// HookError is the error of a shutdown hook which failed
+type HookError struct {
+	Hook string
+	Err  error
+}
+
+func (e *HookError) Error() string {
+	return fmt.Sprintf("shutdown hook %s: %v", e.Hook, e.Err)
+}
+
+func (e *HookError) Unwrap() error {
+	return e.Err
+}
+
+// ShutdownOptions configures a Shutdown
+type ShutdownOptions struct {
+	// Grace is how long the hooks may drain before the hard context is aborted
+	Grace time.Duration
+	// Clock defaults to SystemClock
+	Clock Clock
+}
+
+// Shutdown coordinates a two-phase shutdown: Start aborts the drain context so that
+// components stop accepting work, runs the hooks in dependency order, and aborts
+// the hard context with ErrGraceExpired if they have not completed within the grace period
+type Shutdown struct {
+	opts      ShutdownOptions
+	drain     AbortContext
+	drainFunc AbortFunc
+	hard      AbortContext
+	hardFunc  AbortFunc
+	mu        sync.Mutex
+	hooks     []ShutdownHook
+	names     map[string]struct{}
+	done      Future
+	started   bool
+}
+
+// NewShutdown creates a coordinator whose contexts also end with ctx
+func NewShutdown(ctx context.Context, opts ShutdownOptions) *Shutdown {
+	if opts.Clock == nil {
+		opts.Clock = SystemClock
+	}
+	hard, hardFunc := WithAbort(ctx)
+	// the drain context is a child so that a hard abort also ends draining
+	drain, drainFunc := WithAbort(hard)
+	return &Shutdown{
+		opts:      opts,
+		drain:     drain,
+		drainFunc: drainFunc,
+		hard:      hard,
+		hardFunc:  hardFunc,
+		names:     make(map[string]struct{}),
+	}
+}
+
+// Drain returns the context which is aborted once the shutdown starts
+func (s *Shutdown) Drain() AbortContext {
+	return s.drain
+}
+
+// Hard returns the context which is aborted once the grace period has passed
+func (s *Shutdown) Hard() AbortContext {
+	return s.hard
+}
+
+// Register adds a hook; the hooks it runs after must already be registered,
+// which also rules out dependency cycles
+func (s *Shutdown) Register(hook ShutdownHook) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return ErrShutdownStarted
+	}
+	if _, ok := s.names[hook.Name]; ok {
+		return fmt.Errorf("%w: %s", ErrDuplicateHook, hook.Name)
+	}
+	for _, dep := range hook.After {
+		if _, ok := s.names[dep]; !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownHook, dep)
+		}
+	}
+	s.names[hook.Name] = struct{}{}
+	s.hooks = append(s.hooks, hook)
+	return nil
+}
+
+// Start aborts the drain context with reason and runs the hooks; the returned
+// future completes once every hook has completed, failing with the joined
+// HookErrors of the hooks which failed. Later calls return the same future.
+func (s *Shutdown) Start(reason error) Future {
+	s.mu.Lock()
+	if s.started {
+		s.mu.Unlock()
+		return s.done
+	}
+	s.started = true
+	done, completeFunc := NewFuture()
+	s.done = done
+	hooks := s.hooks
+	s.mu.Unlock()
+
+	s.drainFunc(reason)
+	expired := s.opts.Clock.After(s.opts.Grace)
+	go func() {
+		select {
+		case <-expired:
+			s.hardFunc(ErrGraceExpired)
+		case <-done.Done():
+		}
+	}()
+
+	stopped := make(map[string]Future, len(hooks))
+	for _, hook := range hooks {
+		deps := make([]Future, 0, len(hook.After))
+		for _, dep := range hook.After {
+			deps = append(deps, stopped[dep])
+		}
+		stopped[hook.Name] = s.run(hook, deps)
+	}
+	go func() {
+		var errs []error
+		for _, hook := range hooks {
+			if _, err := stopped[hook.Name].Result(); err != nil {
+				errs = append(errs, &HookError{Hook: hook.Name, Err: err})
+			}
+		}
+		completeFunc(nil, errors.Join(errs...))
+	}()
+	return done
+}
+
+// run calls the hook once its dependencies have completed, whether or not they failed
+func (s *Shutdown) run(hook ShutdownHook, deps []Future) Future {
+	future, completeFunc := NewFuture()
+	go func() {
+		for _, dep := range deps {
+			dep.Result()
+		}
+		val, err := runTask(s.hard, func(ctx AbortContext) (interface{}, error) {
+			return hook.Fn(ctx).Result()
+		})
+		completeFunc(val, err)
+	}()
+	return future
+}
@@ -0,0 +1,99 @@
+package futures
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func shutdownHook(name string, after []string, fn func(ctx AbortContext) error) ShutdownHook {
+	return ShutdownHook{
+		Name:  name,
+		After: after,
+		Fn: func(ctx AbortContext) Future {
+			future, completeFunc := NewFuture()
+			go func() {
+				completeFunc(nil, fn(ctx))
+			}()
+			return future
+		},
+	}
+}
+
+func TestShutdownOrder(t *testing.T) {
+	shutdown := NewShutdown(context.Background(), ShutdownOptions{Grace: time.Minute})
+	var mu sync.Mutex
+	var order []string
+	record := func(name string) func(AbortContext) error {
+		return func(AbortContext) error {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, name)
+			return nil
+		}
+	}
+	require.NoError(t, shutdown.Register(shutdownHook("http", nil, record("http"))))
+	require.NoError(t, shutdown.Register(shutdownHook("workers", []string{"http"}, record("workers"))))
+	require.NoError(t, shutdown.Register(shutdownHook("db", []string{"http", "workers"}, record("db"))))
+	_, err := shutdown.Start(errors.New("TestShutdownOrder")).Result()
+	require.NoError(t, err)
+	require.Equal(t, []string{"http", "workers", "db"}, order)
+	require.Nil(t, shutdown.Hard().Err())
+}
+
+func TestShutdownDrain(t *testing.T) {
+	shutdown := NewShutdown(context.Background(), ShutdownOptions{Grace: time.Minute})
+	expectedErr := errors.New("TestShutdownDrain")
+	require.NoError(t, shutdown.Register(shutdownHook("http", nil, func(AbortContext) error {
+		return shutdown.Drain().Err()
+	})))
+	require.Nil(t, shutdown.Drain().Err())
+	_, err := shutdown.Start(expectedErr).Result()
+	require.True(t, errors.Is(err, expectedErr))
+	var hookErr *HookError
+	require.True(t, errors.As(err, &hookErr))
+	require.Equal(t, "http", hookErr.Hook)
+}
+
+func TestShutdownGraceExpired(t *testing.T) {
+	clock := newFakeClock()
+	shutdown := NewShutdown(context.Background(), ShutdownOptions{Grace: time.Minute, Clock: clock})
+	require.NoError(t, shutdown.Register(shutdownHook("stuck", nil, func(ctx AbortContext) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})))
+	done := shutdown.Start(errors.New("TestShutdownGraceExpired"))
+	requirePending(t, done)
+	clock.Advance(time.Minute)
+	_, err := done.Result()
+	require.True(t, errors.Is(err, ErrGraceExpired))
+	require.Equal(t, ErrGraceExpired, shutdown.Hard().Err())
+}
+
+func TestShutdownRegister(t *testing.T) {
+	shutdown := NewShutdown(context.Background(), ShutdownOptions{Grace: time.Minute})
+	noop := func(AbortContext) error { return nil }
+	require.True(t, errors.Is(shutdown.Register(shutdownHook("db", []string{"http"}, noop)), ErrUnknownHook))
+	require.NoError(t, shutdown.Register(shutdownHook("http", nil, noop)))
+	require.True(t, errors.Is(shutdown.Register(shutdownHook("http", nil, noop)), ErrDuplicateHook))
+	first := shutdown.Start(nil)
+	require.Same(t, first, shutdown.Start(nil))
+	require.Equal(t, ErrShutdownStarted, shutdown.Register(shutdownHook("db", nil, noop)))
+}
+
+func TestShutdownHookPanic(t *testing.T) {
+	shutdown := NewShutdown(context.Background(), ShutdownOptions{Grace: time.Minute})
+	require.NoError(t, shutdown.Register(ShutdownHook{
+		Name: "TestShutdownHookPanic",
+		Fn: func(AbortContext) Future {
+			panic("TestShutdownHookPanic")
+		},
+	}))
+	_, err := shutdown.Start(nil).Result()
+	var panicErr *PanicError
+	require.True(t, errors.As(err, &panicErr))
+}
@@ -0,0 +1,49 @@
+package futures
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// SignalError is the abort error of a context ended by an OS signal
+type SignalError struct {
+	Signal os.Signal
+}
+
+func (e *SignalError) Error() string {
+	return fmt.Sprintf("received signal %v", e.Signal)
+}
+
+// WithSignalAbort aborts the drain context with a SignalError on the first of the
+// signals, defaulting to SIGINT and SIGTERM, and the hard context on the second.
+// The drain context is a child of the hard context, and abortFunc aborts both.
+// Signal delivery stops once the hard context is done.
+func WithSignalAbort(ctx context.Context, signals ...os.Signal) (drain AbortContext, hard AbortContext, abortFunc AbortFunc) {
+	if len(signals) == 0 {
+		signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	hard, hardFunc := WithAbort(ctx)
+	drain, drainFunc := WithAbort(hard)
+	received := make(chan os.Signal, 1)
+	signal.Notify(received, signals...)
+	go func() {
+		defer signal.Stop(received)
+		for count := 0; ; count++ {
+			select {
+			case sig := <-received:
+				if count == 0 {
+					drainFunc(&SignalError{Signal: sig})
+					continue
+				}
+				hardFunc(&SignalError{Signal: sig})
+				return
+			case <-hard.Done():
+				return
+			}
+		}
+	}()
+	return drain, hard, hardFunc
+}
@@ -0,0 +1,51 @@
+//go:build !windows
+
+package futures
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignalAbort(t *testing.T) {
+	drain, hard, abortFunc := WithSignalAbort(context.Background(), syscall.SIGUSR1)
+	defer abortFunc(nil)
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR1))
+	select {
+	case <-drain.Done():
+	case <-time.After(time.Second):
+		t.Fatal("abort not completed as expected")
+	}
+	var signalErr *SignalError
+	require.True(t, errors.As(drain.Err(), &signalErr))
+	require.Equal(t, syscall.SIGUSR1, signalErr.Signal)
+	require.Nil(t, hard.Err())
+}
+
+func TestSignalAbortEscalate(t *testing.T) {
+	drain, hard, abortFunc := WithSignalAbort(context.Background(), syscall.SIGUSR2)
+	defer abortFunc(nil)
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR2))
+	<-drain.Done()
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR2))
+	select {
+	case <-hard.Done():
+	case <-time.After(time.Second):
+		t.Fatal("abort not completed as expected")
+	}
+	require.Equal(t, &SignalError{Signal: syscall.SIGUSR2}, hard.Err())
+}
+
+func TestSignalAbortParent(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	drain, hard, _ := WithSignalAbort(ctx, syscall.SIGUSR1)
+	cancel()
+	<-drain.Done()
+	<-hard.Done()
+	require.Equal(t, context.Canceled, drain.Err())
+}
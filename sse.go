@@ -0,0 +1,302 @@
+package futures
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	sseEventEnd   = "end"
+	sseEventError = "error"
+	sseRetryDelay = 1 * time.Second
+)
+
+// SSEHandler serves the items of the stream to each request as server-sent events.
+// The handler takes ownership of the stream: it consumes it and keeps the replay
+// most recent items, so event IDs are sequence numbers which stay stable for the
+// lifetime of the handler. Requests without Last-Event-ID start at the oldest kept
+// item, and requests resuming from an item which is no longer kept fail with 410 Gone.
+func SSEHandler(stream Stream, codec Codec, replay int) http.Handler {
+	events, sendFunc := NewStream()
+	// the base reader only serves as a source of clones, so it must not buffer items
+	events.Close()
+	h := &sseHandler{
+		codec:    codec,
+		replay:   replay,
+		events:   events,
+		sendFunc: sendFunc,
+	}
+	go h.consume(stream)
+	return h
+}
+
+type sseHandler struct {
+	codec    Codec
+	replay   int
+	mu       sync.Mutex
+	first    int
+	items    []interface{}
+	events   Stream
+	sendFunc SendFunc
+}
+
+// sseItem is an item of the source stream with its sequence number
+type sseItem struct {
+	seq  int
+	item interface{}
+}
+
+// consume moves the items of the stream into the replay buffer and to the requests
+func (h *sseHandler) consume(stream Stream) {
+	defer stream.Close()
+	for {
+		item, err := stream.Next()
+		h.mu.Lock()
+		if err != nil {
+			h.sendFunc(nil, err)
+			h.mu.Unlock()
+			return
+		}
+		h.sendFunc(sseItem{seq: h.first + len(h.items), item: item}, nil)
+		h.items = append(h.items, item)
+		if len(h.items) > h.replay {
+			h.items[0] = nil
+			h.items = h.items[1:]
+			h.first++
+		}
+		h.mu.Unlock()
+	}
+}
+
+func (h *sseHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	start := -1
+	if lastID := r.Header.Get("Last-Event-ID"); lastID != "" {
+		seq, err := strconv.Atoi(lastID)
+		if err != nil || seq < 0 {
+			http.Error(w, "invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+		start = seq + 1
+	}
+	// the kept items and the clone are taken together so that no item is missed or repeated
+	h.mu.Lock()
+	if start < 0 {
+		start = h.first
+	}
+	if start < h.first {
+		h.mu.Unlock()
+		http.Error(w, "Last-Event-ID no longer available", http.StatusGone)
+		return
+	}
+	var replayed []sseItem
+	for i := start - h.first; i < len(h.items); i++ {
+		replayed = append(replayed, sseItem{seq: h.first + i, item: h.items[i]})
+	}
+	clone := h.events.Clone()
+	h.mu.Unlock()
+	defer clone.Close()
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	for _, item := range replayed {
+		if !h.write(w, item) {
+			flusher.Flush()
+			return
+		}
+	}
+	flusher.Flush()
+	for {
+		select {
+		case <-clone.Pending():
+		case <-r.Context().Done():
+			return
+		}
+		next, err := clone.Next()
+		if err != nil {
+			event := sseEventError
+			if err == io.EOF {
+				event = sseEventEnd
+			}
+			writeSSE(w, event, "", []byte(err.Error()))
+			flusher.Flush()
+			return
+		}
+		item := next.(sseItem)
+		if item.seq < start {
+			continue
+		}
+		ok := h.write(w, item)
+		flusher.Flush()
+		if !ok {
+			return
+		}
+	}
+}
+
+// write writes the item as an event, or an error event if it cannot be marshaled
+func (h *sseHandler) write(w io.Writer, item sseItem) bool {
+	data, err := h.codec.Marshal(item.item)
+	if err != nil {
+		writeSSE(w, sseEventError, "", []byte(err.Error()))
+		return false
+	}
+	writeSSE(w, "", strconv.Itoa(item.seq), data)
+	return true
+}
+
+func writeSSE(w io.Writer, event string, id string, data []byte) {
+	var buf bytes.Buffer
+	if id != "" {
+		fmt.Fprintf(&buf, "id: %s\n", id)
+	}
+	if event != "" {
+		fmt.Fprintf(&buf, "event: %s\n", event)
+	}
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		fmt.Fprintf(&buf, "data: %s\n", line)
+	}
+	buf.WriteString("\n")
+	w.Write(buf.Bytes())
+}
+
+// SSEStream reads server-sent events from url into a stream. Dropped connections
+// are resumed with Last-Event-ID and the stream ends with ctx.Err() once ctx is done.
+func SSEStream(ctx context.Context, client *http.Client, url string, codec Codec) Stream {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	stream, sendFunc := NewStream()
+	reader := &sseReader{
+		client:   client,
+		url:      url,
+		codec:    codec,
+		sendFunc: sendFunc,
+		retry:    sseRetryDelay,
+	}
+	go func() {
+		sendFunc(nil, reader.run(ctx))
+	}()
+	return stream
+}
+
+type sseReader struct {
+	client   *http.Client
+	url      string
+	codec    Codec
+	sendFunc SendFunc
+	lastID   string
+	retry    time.Duration
+}
+
+type sseEvent struct {
+	id    string
+	event string
+	data  []string
+}
+
+func (s *sseReader) run(ctx context.Context) error {
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Accept", "text/event-stream")
+		if s.lastID != "" {
+			req.Header.Set("Last-Event-ID", s.lastID)
+		}
+		resp, err := s.client.Do(req)
+		if err == nil {
+			if resp.StatusCode != http.StatusOK {
+				resp.Body.Close()
+				return fmt.Errorf("unexpected status %s", resp.Status)
+			}
+			done, err := s.read(resp.Body)
+			resp.Body.Close()
+			if done {
+				return err
+			}
+		}
+		// the connection dropped without an end event so reconnect and resume
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(s.retry):
+		}
+	}
+}
+
+// read dispatches events until the body ends; done reports a terminal event
+func (s *sseReader) read(body io.Reader) (done bool, err error) {
+	reader := bufio.NewReader(body)
+	event := sseEvent{}
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return false, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			if done, err := s.dispatch(event); done {
+				return true, err
+			}
+			event = sseEvent{}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value := line, ""
+		if i := strings.Index(line, ":"); i >= 0 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+		switch field {
+		case "id":
+			event.id = value
+		case "event":
+			event.event = value
+		case "data":
+			event.data = append(event.data, value)
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil && ms >= 0 {
+				s.retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+}
+
+func (s *sseReader) dispatch(event sseEvent) (done bool, err error) {
+	if event.data == nil {
+		return false, nil
+	}
+	data := strings.Join(event.data, "\n")
+	switch event.event {
+	case sseEventEnd:
+		return true, io.EOF
+	case sseEventError:
+		return true, errors.New(data)
+	}
+	item, err := s.codec.Unmarshal([]byte(data))
+	if err != nil {
+		return true, err
+	}
+	if event.id != "" {
+		s.lastID = event.id
+	}
+	s.sendFunc(item, nil)
+	return false, nil
+}
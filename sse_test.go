@@ -0,0 +1,175 @@
+package futures
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSSEHandlerEvents(t *testing.T) {
+	stream, sendFunc := NewStream()
+	sendFunc("TestSSEHandlerEvents", nil)
+	sendFunc(nil, io.EOF)
+	recorder := httptest.NewRecorder()
+	SSEHandler(stream, JSONCodec, 10).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, "text/event-stream", recorder.Header().Get("Content-Type"))
+	require.Equal(t, "id: 0\ndata: \"TestSSEHandlerEvents\"\n\nevent: end\ndata: EOF\n\n", recorder.Body.String())
+}
+
+func TestSSEHandlerLastEventID(t *testing.T) {
+	stream, sendFunc := NewStream()
+	sendFunc("TestSSEHandlerLastEventID1", nil)
+	sendFunc("TestSSEHandlerLastEventID2", nil)
+	sendFunc(nil, errors.New("TestSSEHandlerLastEventID"))
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Last-Event-ID", "0")
+	SSEHandler(stream, JSONCodec, 10).ServeHTTP(recorder, req)
+	require.Equal(t, "id: 1\ndata: \"TestSSEHandlerLastEventID2\"\n\nevent: error\ndata: TestSSEHandlerLastEventID\n\n", recorder.Body.String())
+}
+
+func TestSSEHandlerReplay(t *testing.T) {
+	stream, sendFunc := NewStream()
+	handler := SSEHandler(stream, JSONCodec, 2)
+	for i := 1; i <= 3; i++ {
+		sendFunc(fmt.Sprintf("TestSSEHandlerReplay%d", i), nil)
+	}
+	sendFunc(nil, io.EOF)
+	waitSSESettled(t, handler, 1)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, "id: 1\ndata: \"TestSSEHandlerReplay2\"\n\nid: 2\ndata: \"TestSSEHandlerReplay3\"\n\nevent: end\ndata: EOF\n\n", recorder.Body.String())
+	recorder = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	handler.ServeHTTP(recorder, req)
+	require.Equal(t, "id: 2\ndata: \"TestSSEHandlerReplay3\"\n\nevent: end\ndata: EOF\n\n", recorder.Body.String())
+}
+
+func TestSSEHandlerResumeGone(t *testing.T) {
+	stream, sendFunc := NewStream()
+	handler := SSEHandler(stream, JSONCodec, 1)
+	for i := 1; i <= 3; i++ {
+		sendFunc(fmt.Sprintf("TestSSEHandlerResumeGone%d", i), nil)
+	}
+	waitSSESettled(t, handler, 2)
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Last-Event-ID", "0")
+	handler.ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusGone, recorder.Code)
+}
+
+// waitSSESettled waits until the handler has dropped first items from its replay buffer
+func waitSSESettled(t *testing.T, handler http.Handler, first int) {
+	h := handler.(*sseHandler)
+	require.Eventually(t, func() bool {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		return h.first == first
+	}, time.Second, time.Millisecond)
+}
+
+func TestSSEHandlerInvalidLastEventID(t *testing.T) {
+	stream, _ := NewStream()
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Last-Event-ID", "abc")
+	SSEHandler(stream, JSONCodec, 10).ServeHTTP(recorder, req)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestSSEStreamItems(t *testing.T) {
+	stream, sendFunc := NewStream()
+	server := httptest.NewServer(SSEHandler(stream, JSONCodec, 10))
+	defer server.Close()
+	remote := SSEStream(context.Background(), server.Client(), server.URL, JSONCodec)
+	defer remote.Close()
+	sendFunc("TestSSEStreamItems", nil)
+	sendFunc(nil, io.EOF)
+	item, err := remote.Next()
+	require.NoError(t, err)
+	require.Equal(t, "TestSSEStreamItems", item)
+	item, err = remote.Next()
+	require.Equal(t, io.EOF, err)
+	require.Equal(t, nil, item)
+}
+
+func TestSSEStreamErr(t *testing.T) {
+	stream, sendFunc := NewStream()
+	sendFunc(nil, errors.New("TestSSEStreamErr"))
+	server := httptest.NewServer(SSEHandler(stream, JSONCodec, 10))
+	defer server.Close()
+	remote := SSEStream(context.Background(), server.Client(), server.URL, JSONCodec)
+	defer remote.Close()
+	_, err := remote.Next()
+	require.EqualError(t, err, "TestSSEStreamErr")
+}
+
+func TestSSEStreamStatus(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	remote := SSEStream(context.Background(), server.Client(), server.URL, JSONCodec)
+	defer remote.Close()
+	_, err := remote.Next()
+	require.EqualError(t, err, "unexpected status 404 Not Found")
+}
+
+func TestSSEStreamResume(t *testing.T) {
+	stream, sendFunc := NewStream()
+	sendFunc("TestSSEStreamResume1", nil)
+	sendFunc("TestSSEStreamResume2", nil)
+	sendFunc(nil, io.EOF)
+	handler := SSEHandler(stream, JSONCodec, 10)
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			// drop the connection after the first event
+			w.Write([]byte("retry: 1\nid: 0\ndata: \"TestSSEStreamResume1\"\n\n"))
+			return
+		}
+		require.Equal(t, "0", r.Header.Get("Last-Event-ID"))
+		handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	remote := SSEStream(context.Background(), server.Client(), server.URL, JSONCodec)
+	defer remote.Close()
+	item, err := remote.Next()
+	require.NoError(t, err)
+	require.Equal(t, "TestSSEStreamResume1", item)
+	item, err = remote.Next()
+	require.NoError(t, err)
+	require.Equal(t, "TestSSEStreamResume2", item)
+	_, err = remote.Next()
+	require.Equal(t, io.EOF, err)
+	require.Equal(t, 2, requests)
+}
+
+func TestSSEStreamCancel(t *testing.T) {
+	stream, _ := NewStream()
+	handler := SSEHandler(stream, JSONCodec, 10)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	remote := SSEStream(ctx, server.Client(), server.URL, JSONCodec)
+	defer remote.Close()
+	tracker := handler.(*sseHandler).events.(*streamReader).streamTracker
+	readers := func() int {
+		tracker.RLock()
+		defer tracker.RUnlock()
+		return len(tracker.readers)
+	}
+	require.Eventually(t, func() bool { return readers() == 1 }, time.Second, time.Millisecond)
+	cancel()
+	_, err := remote.Next()
+	require.Equal(t, context.Canceled, err)
+	require.Eventually(t, func() bool { return readers() == 0 }, time.Second, time.Millisecond)
+}
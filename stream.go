@@ -28,6 +28,7 @@ func NewStream() (Stream, SendFunc) {
 	reader := &streamReader{
 		streamTracker: tracker,
 	}
+	reader.track(1)
 	tracker.readers[reader] = struct{}{}
 	return reader, tracker.send
 }
@@ -41,8 +42,19 @@ type streamTracker struct {
 
 type streamReader struct {
 	*streamTracker
-	items  []*interface{}
-	closed bool
+	items   []*interface{}
+	closed  bool
+	tracked *trackedObject
+}
+
+// track records the reader if tracking is enabled; skip is the number of frames
+// between track and the caller to record
+func (s *streamReader) track(skip int) {
+	s.tracked = track(PendingStream, skip+1, func() int {
+		s.RLock()
+		defer s.RUnlock()
+		return len(s.items)
+	})
 }
 
 func (s *streamTracker) send(item interface{}, err error) {
@@ -83,8 +95,9 @@ func (s *streamReader) Pending() <-chan struct{} {
 
 func (s *streamReader) Next() (interface{}, error) {
 	<-s.Pending()
-	s.RLock()
-	defer s.RUnlock()
+	// the write lock is needed because consuming an item modifies the reader
+	s.Lock()
+	defer s.Unlock()
 	if s.closed {
 		return nil, ErrStreamClosed
 	}
@@ -107,6 +120,7 @@ func (s *streamReader) Clone() Stream {
 	}
 	s.readers[clone] = struct{}{}
 	s.Unlock()
+	clone.track(1)
 	return clone
 }
 
@@ -116,4 +130,7 @@ func (s *streamReader) Close() {
 	s.closed = true
 	delete(s.readers, s)
 	s.Unlock()
+	if s.tracked != nil {
+		s.tracked.untrack()
+	}
 }
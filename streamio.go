@@ -0,0 +1,91 @@
+package futures
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// StreamWriter returns a writer which sends each write to a stream as a []byte item;
+// closing the writer ends the stream with io.EOF
+func StreamWriter(sendFunc SendFunc) io.WriteCloser {
+	return &streamWriter{
+		sendFunc: sendFunc,
+	}
+}
+
+type streamWriter struct {
+	mu       sync.Mutex
+	sendFunc SendFunc
+	closed   bool
+}
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, io.ErrClosedPipe
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	// writers may reuse p after returning so the item must be a copy
+	item := make([]byte, len(p))
+	copy(item, p)
+	w.sendFunc(item, nil)
+	return len(p), nil
+}
+
+func (w *streamWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	w.sendFunc(nil, io.EOF)
+	return nil
+}
+
+// StreamReader returns a reader which concatenates the []byte items of a stream;
+// the stream error is returned once all items have been read
+func StreamReader(stream Stream) io.ReadCloser {
+	return &streamReadCloser{
+		stream: stream,
+	}
+}
+
+type streamReadCloser struct {
+	stream Stream
+	buf    []byte
+	err    error
+}
+
+func (r *streamReadCloser) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		item, err := r.stream.Next()
+		if err != nil {
+			r.err = err
+			continue
+		}
+		buf, ok := item.([]byte)
+		if !ok {
+			r.err = fmt.Errorf("unexpected stream item type %T", item)
+			continue
+		}
+		r.buf = buf
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *streamReadCloser) Close() error {
+	r.stream.Close()
+	r.buf = nil
+	r.err = ErrStreamClosed
+	return nil
+}
@@ -0,0 +1,86 @@
+package futures
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamWriterItems(t *testing.T) {
+	stream, sendFunc := NewStream()
+	writer := StreamWriter(sendFunc)
+	buf := []byte("TestStreamWriterItems")
+	n, err := writer.Write(buf)
+	require.NoError(t, err)
+	require.Equal(t, len(buf), n)
+	buf[0] = 'X'
+	item, err := stream.Next()
+	require.NoError(t, err)
+	require.Equal(t, []byte("TestStreamWriterItems"), item)
+}
+
+func TestStreamWriterClose(t *testing.T) {
+	stream, sendFunc := NewStream()
+	writer := StreamWriter(sendFunc)
+	require.NoError(t, writer.Close())
+	require.NoError(t, writer.Close())
+	_, err := writer.Write([]byte("TestStreamWriterClose"))
+	require.Equal(t, io.ErrClosedPipe, err)
+	item, err := stream.Next()
+	require.Equal(t, io.EOF, err)
+	require.Equal(t, nil, item)
+}
+
+func TestStreamReaderConcat(t *testing.T) {
+	stream, sendFunc := NewStream()
+	writer := StreamWriter(sendFunc)
+	writer.Write([]byte("Test"))
+	writer.Write([]byte("Stream"))
+	writer.Write([]byte("ReaderConcat"))
+	writer.Close()
+	data, err := ioutil.ReadAll(StreamReader(stream))
+	require.NoError(t, err)
+	require.Equal(t, "TestStreamReaderConcat", string(data))
+}
+
+func TestStreamReaderShortBuffer(t *testing.T) {
+	stream, sendFunc := NewStream()
+	sendFunc([]byte("TestStreamReaderShortBuffer"), nil)
+	reader := StreamReader(stream)
+	buf := make([]byte, 4)
+	n, err := reader.Read(buf)
+	require.NoError(t, err)
+	require.Equal(t, "Test", string(buf[:n]))
+	n, err = reader.Read(buf)
+	require.NoError(t, err)
+	require.Equal(t, "Stre", string(buf[:n]))
+}
+
+func TestStreamReaderErr(t *testing.T) {
+	stream, sendFunc := NewStream()
+	expectedErr := errors.New("TestStreamReaderErr")
+	sendFunc([]byte("TestStreamReaderErr"), nil)
+	sendFunc(nil, expectedErr)
+	data, err := ioutil.ReadAll(StreamReader(stream))
+	require.EqualError(t, err, expectedErr.Error())
+	require.Equal(t, "TestStreamReaderErr", string(data))
+}
+
+func TestStreamReaderItemType(t *testing.T) {
+	stream, sendFunc := NewStream()
+	sendFunc("TestStreamReaderItemType", nil)
+	_, err := StreamReader(stream).Read(make([]byte, 8))
+	require.EqualError(t, err, "unexpected stream item type string")
+}
+
+func TestStreamReaderClose(t *testing.T) {
+	stream, sendFunc := NewStream()
+	sendFunc([]byte("TestStreamReaderClose"), nil)
+	reader := StreamReader(stream)
+	require.NoError(t, reader.Close())
+	_, err := reader.Read(make([]byte, 8))
+	require.Equal(t, ErrStreamClosed, err)
+}
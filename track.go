@@ -0,0 +1,207 @@
+package futures
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// PendingFuture is the kind of a tracked future which has not been completed
+	PendingFuture = "future"
+	// PendingStream is the kind of a tracked stream reader which has not been closed
+	PendingStream = "stream"
+)
+
+// PendingObject is a tracked future or stream reader which is still pending
+type PendingObject struct {
+	ID      uint64    `json:"id"`
+	Kind    string    `json:"kind"`
+	Created time.Time `json:"created"`
+	Stack   string    `json:"stack"`
+	// Backlog is the number of items sent to a stream reader but not yet consumed
+	Backlog int `json:"backlog,omitempty"`
+}
+
+// PendingFilter selects pending objects; the zero value selects all of them
+type PendingFilter struct {
+	// OlderThan selects objects created at least this long ago
+	OlderThan time.Duration
+	// MinBacklog selects stream readers with at least this many unconsumed items
+	MinBacklog int
+}
+
+// currentTracker holds the *tracker which records new futures and streams, or a nil one if disabled
+var currentTracker atomic.Value
+
+var publishOnce sync.Once
+
+func init() {
+	currentTracker.Store((*tracker)(nil))
+}
+
+// EnableTracking starts recording the creation stack of the futures, streams and
+// clones created after this call until they are completed or closed, and
+// publishes their counts as the futures.pending expvar
+func EnableTracking() {
+	currentTracker.Store(&tracker{
+		objects: make(map[*trackedObject]struct{}),
+	})
+	publishOnce.Do(func() {
+		expvar.Publish("futures.pending", expvar.Func(pendingCounts))
+	})
+}
+
+// DisableTracking stops recording futures and streams and drops the records
+func DisableTracking() {
+	currentTracker.Store((*tracker)(nil))
+}
+
+// PendingObjects returns the tracked objects selected by filter ordered by creation
+func PendingObjects(filter PendingFilter) []PendingObject {
+	t := currentTracker.Load().(*tracker)
+	if t == nil {
+		return nil
+	}
+	now := time.Now()
+	t.mu.Lock()
+	objects := make([]*trackedObject, 0, len(t.objects))
+	for object := range t.objects {
+		objects = append(objects, object)
+	}
+	t.mu.Unlock()
+	var pending []PendingObject
+	for _, object := range objects {
+		backlog := 0
+		if object.backlog != nil {
+			backlog = object.backlog()
+		}
+		if now.Sub(object.created) < filter.OlderThan || backlog < filter.MinBacklog {
+			continue
+		}
+		pending = append(pending, PendingObject{
+			ID:      object.id,
+			Kind:    object.kind,
+			Created: object.created,
+			Stack:   object.stack(),
+			Backlog: backlog,
+		})
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].ID < pending[j].ID
+	})
+	return pending
+}
+
+// PendingHandler renders the PendingObjects as text, or as JSON if the format
+// query parameter is json; the older_than and min_backlog query parameters set
+// the filter
+func PendingHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		query := req.URL.Query()
+		var filter PendingFilter
+		if olderThan := query.Get("older_than"); olderThan != "" {
+			d, err := time.ParseDuration(olderThan)
+			if err != nil {
+				http.Error(w, "invalid older_than", http.StatusBadRequest)
+				return
+			}
+			filter.OlderThan = d
+		}
+		if minBacklog := query.Get("min_backlog"); minBacklog != "" {
+			n, err := strconv.Atoi(minBacklog)
+			if err != nil {
+				http.Error(w, "invalid min_backlog", http.StatusBadRequest)
+				return
+			}
+			filter.MinBacklog = n
+		}
+		pending := PendingObjects(filter)
+		if query.Get("format") == "json" {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(pending)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		for _, object := range pending {
+			fmt.Fprintf(w, "#%d %s created %s", object.ID, object.Kind, object.Created.Format(time.RFC3339Nano))
+			if object.Kind == PendingStream {
+				fmt.Fprintf(w, " backlog %d", object.Backlog)
+			}
+			fmt.Fprintf(w, "\n%s\n", object.Stack)
+		}
+	})
+}
+
+func pendingCounts() interface{} {
+	counts := map[string]int{PendingFuture: 0, PendingStream: 0, "backlog": 0}
+	for _, object := range PendingObjects(PendingFilter{}) {
+		counts[object.Kind]++
+		counts["backlog"] += object.Backlog
+	}
+	return counts
+}
+
+type tracker struct {
+	mu      sync.Mutex
+	lastID  uint64
+	objects map[*trackedObject]struct{}
+}
+
+type trackedObject struct {
+	tracker *tracker
+	id      uint64
+	kind    string
+	created time.Time
+	pcs     []uintptr
+	backlog func() int
+}
+
+// track records a new object of kind if tracking is enabled; skip is the number
+// of frames between track and the caller to record, and backlog is nil for futures
+func track(kind string, skip int, backlog func() int) *trackedObject {
+	t := currentTracker.Load().(*tracker)
+	if t == nil {
+		return nil
+	}
+	pcs := make([]uintptr, 32)
+	object := &trackedObject{
+		tracker: t,
+		kind:    kind,
+		created: time.Now(),
+		pcs:     pcs[:runtime.Callers(skip+2, pcs)],
+		backlog: backlog,
+	}
+	t.mu.Lock()
+	t.lastID++
+	object.id = t.lastID
+	t.objects[object] = struct{}{}
+	t.mu.Unlock()
+	return object
+}
+
+func (o *trackedObject) untrack() {
+	o.tracker.mu.Lock()
+	delete(o.tracker.objects, o)
+	o.tracker.mu.Unlock()
+}
+
+func (o *trackedObject) stack() string {
+	var stack strings.Builder
+	frames := runtime.CallersFrames(o.pcs)
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&stack, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			return stack.String()
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package futures
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrackFutures(t *testing.T) {
+	EnableTracking()
+	defer DisableTracking()
+	pending, _ := NewFuture()
+	_, completeFunc := NewFuture()
+	completeFunc(nil, nil)
+	objects := PendingObjects(PendingFilter{})
+	require.Len(t, objects, 1)
+	require.Equal(t, PendingFuture, objects[0].Kind)
+	require.True(t, strings.HasPrefix(objects[0].Stack, "github.com/kevindejong/futures.TestTrackFutures\n"), objects[0].Stack)
+	require.NotNil(t, pending)
+}
+
+func TestTrackStreams(t *testing.T) {
+	EnableTracking()
+	defer DisableTracking()
+	stream, sendFunc := NewStream()
+	clone := stream.Clone()
+	sendFunc("TestTrackStreams", nil)
+	stream.Close()
+	objects := PendingObjects(PendingFilter{})
+	require.Len(t, objects, 1)
+	require.Equal(t, PendingStream, objects[0].Kind)
+	require.Equal(t, 1, objects[0].Backlog)
+	require.True(t, strings.HasPrefix(objects[0].Stack, "github.com/kevindejong/futures.TestTrackStreams\n"), objects[0].Stack)
+	clone.Next()
+	require.Empty(t, PendingObjects(PendingFilter{MinBacklog: 1}))
+	clone.Close()
+	require.Empty(t, PendingObjects(PendingFilter{}))
+}
+
+func TestTrackOlderThan(t *testing.T) {
+	EnableTracking()
+	defer DisableTracking()
+	NewFuture()
+	time.Sleep(10 * time.Millisecond)
+	NewFuture()
+	require.Len(t, PendingObjects(PendingFilter{}), 2)
+	require.Len(t, PendingObjects(PendingFilter{OlderThan: 10 * time.Millisecond}), 1)
+}
+
+func TestTrackDisabled(t *testing.T) {
+	fut, _ := NewFuture()
+	require.Nil(t, fut.(*future).tracked)
+	require.Nil(t, PendingObjects(PendingFilter{}))
+}
+
+func TestPendingHandler(t *testing.T) {
+	EnableTracking()
+	defer DisableTracking()
+	NewStream()
+	recorder := httptest.NewRecorder()
+	PendingHandler().ServeHTTP(recorder, httptest.NewRequest("GET", "/pending", nil))
+	require.True(t, strings.HasPrefix(recorder.Body.String(), "#1 stream created "), recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	PendingHandler().ServeHTTP(recorder, httptest.NewRequest("GET", "/pending?format=json&older_than=1h", nil))
+	var objects []PendingObject
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &objects))
+	require.Empty(t, objects)
+
+	recorder = httptest.NewRecorder()
+	PendingHandler().ServeHTTP(recorder, httptest.NewRequest("GET", "/pending?min_backlog=x", nil))
+	require.Equal(t, 400, recorder.Code)
+
+	require.Equal(t, `{"backlog":0,"future":0,"stream":1}`, expvar.Get("futures.pending").String())
+}
+
+func TestTrackStreamsConcurrentNext(t *testing.T) {
+	EnableTracking()
+	defer DisableTracking()
+	stream, sendFunc := NewStream()
+	defer stream.Close()
+	for i := 0; i < 100; i++ {
+		sendFunc(i, nil)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			stream.Next()
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		PendingObjects(PendingFilter{})
+	}
+	<-done
+	require.Equal(t, 0, PendingObjects(PendingFilter{})[0].Backlog)
+}